@@ -0,0 +1,60 @@
+package xlog
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// AdminHandler is an http.Handler exposing the log level and the output channel
+// counters as JSON so they can be inspected and changed on a running service.
+//
+// A GET request returns the current state:
+//
+//	{"level": "info", "output": {"queued": 0, "capacity": 100, "dropped": 0}}
+//
+// A PUT or POST request with a body like {"level": "debug"} changes the level
+// and returns the new state.
+type AdminHandler struct {
+	// Level is the level controlled by the handler. It should be the same
+	// AtomicLevel as the one set in the logger's Config.
+	Level *AtomicLevel
+	// Output is the optional output channel to report counters for.
+	Output *OutputChannel
+}
+
+type adminState struct {
+	Level  *AtomicLevel        `json:"level,omitempty"`
+	Output *OutputChannelStats `json:"output,omitempty"`
+}
+
+func (h AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET", "HEAD":
+	case "PUT", "POST":
+		if h.Level == nil {
+			http.Error(w, "level is not configurable", http.StatusNotImplemented)
+			return
+		}
+		var req adminState
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Level == nil {
+			http.Error(w, "invalid request: missing level", http.StatusBadRequest)
+			return
+		}
+		h.Level.SetLevel(req.Level.Level())
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	state := adminState{Level: h.Level}
+	if h.Output != nil {
+		stats := h.Output.Stats()
+		state.Output = &stats
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
@@ -0,0 +1,61 @@
+package xlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminHandlerGet(t *testing.T) {
+	oc := NewOutputChannelBuffer(Discard, 10)
+	defer oc.Close()
+	h := AdminHandler{Level: NewAtomicLevel(xlog.LevelInfo), Output: oc}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"level":"info","output":{"queued":0,"capacity":10,"dropped":0}}`+"\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	AdminHandler{}.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "{}\n", w.Body.String())
+}
+
+func TestAdminHandlerSet(t *testing.T) {
+	a := NewAtomicLevel(xlog.LevelInfo)
+	h := AdminHandler{Level: a}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PUT", "/", strings.NewReader(`{"level":"debug"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"level":"debug"}`+"\n", w.Body.String())
+	assert.Equal(t, xlog.LevelDebug, a.Level())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"level":"error"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, xlog.LevelError, a.Level())
+}
+
+func TestAdminHandlerErrors(t *testing.T) {
+	a := NewAtomicLevel(xlog.LevelInfo)
+	h := AdminHandler{Level: a}
+	for _, body := range []string{`{"level":"foo"}`, `{}`, `not json`} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("PUT", "/", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.Equal(t, xlog.LevelInfo, a.Level())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD, PUT, POST", w.Header().Get("Allow"))
+
+	w = httptest.NewRecorder()
+	AdminHandler{}.ServeHTTP(w, httptest.NewRequest("PUT", "/", strings.NewReader(`{"level":"debug"}`)))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -0,0 +1,135 @@
+package xlog
+
+import (
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// callerConfig defines how the location of the log call is recorded.
+type callerConfig struct {
+	// disable disables the capture of the caller
+	disable bool
+	// modulePath records the file path relative to the main module instead of
+	// the file base name
+	modulePath bool
+	// funcName records the name of the calling function in the func field
+	funcName bool
+	// skip is the number of additional frames to skip
+	skip int
+}
+
+// mainModule is the path of the main module or an empty string if unknown.
+var mainModule = readMainModule()
+
+// callerPC returns the program counter of the function calldepth frames above
+// the caller of callerPC, or 0 if the caller capture is disabled.
+func (c callerConfig) callerPC(calldepth int) uintptr {
+	if c.disable {
+		return 0
+	}
+	var pcs [1]uintptr
+	if runtime.Callers(calldepth+c.skip+2, pcs[:]) == 1 {
+		return pcs[0]
+	}
+	return 0
+}
+
+// callerFrame is the location of a program counter.
+type callerFrame struct {
+	file     string
+	line     int
+	function string
+	// modFile is the file relative to the main module followed by the line
+	modFile string
+}
+
+// callerFrames caches the location of the program counters of the log calls so
+// they are resolved without allocating once seen.
+var callerFrames = struct {
+	sync.RWMutex
+	m map[uintptr]*callerFrame
+}{m: map[uintptr]*callerFrame{}}
+
+// frameOf returns the location of pc.
+func frameOf(pc uintptr) *callerFrame {
+	callerFrames.RLock()
+	f := callerFrames.m[pc]
+	callerFrames.RUnlock()
+	if f != nil {
+		return f
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	f = &callerFrame{
+		file:     frame.File,
+		line:     frame.Line,
+		function: frame.Function,
+		modFile:  modulePath(frame.Function, frame.File) + ":" + strconv.Itoa(frame.Line),
+	}
+	callerFrames.Lock()
+	callerFrames.m[pc] = f
+	callerFrames.Unlock()
+	return f
+}
+
+// appendCaller appends the fields describing the location pc to dst using the
+// field names of keys.
+func (c callerConfig) appendCaller(dst []Field, keys *FieldKeys, pc uintptr) []Field {
+	if pc == 0 {
+		return dst
+	}
+	frame := frameOf(pc)
+	if c.modulePath {
+		dst = append(dst, Field{Key: keys.File, Type: StringType, String: frame.modFile})
+	} else {
+		dst = append(dst, Field{Key: keys.File, Type: CallerType, String: frame.file, Integer: int64(frame.line)})
+	}
+	if c.funcName && frame.function != "" {
+		dst = append(dst, Field{Key: keys.Func, Type: StringType, String: path.Base(frame.function)})
+	}
+	return dst
+}
+
+// modulePath returns the path of file relative to the main module, built from
+// the import path of the package of the fully qualified function name fn. Files
+// of other modules keep their full import path and files of main packages,
+// having no import path, are prefixed by the name of their directory.
+func modulePath(fn, file string) string {
+	base := path.Base(file)
+	pkg := funcPackage(fn, file)
+	switch {
+	case pkg == "":
+		return base
+	case pkg == "main":
+		return path.Base(path.Dir(file)) + "/" + base
+	case pkg == mainModule:
+		return base
+	case mainModule != "" && strings.HasPrefix(pkg, mainModule+"/"):
+		return pkg[len(mainModule)+1:] + "/" + base
+	}
+	return pkg + "/" + base
+}
+
+// funcPackage returns the import path of the package of the fully qualified
+// function name fn defined in file, or an empty string if fn has none. As the
+// last element of the import path may contain dots, like in gopkg.in/yaml.v2,
+// it is read from the directory of file when fn starts with it, without the
+// version of the module cache directories.
+func funcPackage(fn, file string) string {
+	slash := strings.LastIndexByte(fn, '/')
+	name := fn[slash+1:]
+	dir := path.Base(path.Dir(file))
+	if at := strings.IndexByte(dir, '@'); at >= 0 {
+		dir = dir[:at]
+	}
+	if strings.IndexByte(dir, '.') >= 0 && strings.HasPrefix(name, dir+".") {
+		return fn[:slash+1+len(dir)]
+	}
+	dot := strings.IndexByte(name, '.')
+	if dot < 0 {
+		return ""
+	}
+	return fn[:slash+1+dot]
+}
@@ -0,0 +1,13 @@
+// +build go1.12
+
+package xlog
+
+import "runtime/debug"
+
+// readMainModule returns the path of the main module from the build info.
+func readMainModule() string {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		return bi.Main.Path
+	}
+	return ""
+}
@@ -0,0 +1,9 @@
+// +build !go1.12
+
+package xlog
+
+// readMainModule returns an empty string as the build info is only available
+// with Go 1.12+.
+func readMainModule() string {
+	return ""
+}
@@ -0,0 +1,79 @@
+package xlog
+
+import (
+	"path"
+	"testing"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModulePath(t *testing.T) {
+	m := mainModule
+	mainModule = "example.com/app"
+	defer func() { mainModule = m }()
+	assert.Equal(t, "conn.go", modulePath("example.com/app.Open", "/src/app/conn.go"))
+	assert.Equal(t, "internal/db/conn.go", modulePath("example.com/app/internal/db.(*Conn).Close", "/src/app/internal/db/conn.go"))
+	assert.Equal(t, "example.com/lib/v2/lib.go", modulePath("example.com/lib/v2.Do.func1", "/mod/lib/lib.go"))
+	assert.Equal(t, "server/main.go", modulePath("main.main", "/src/app/cmd/server/main.go"))
+	assert.Equal(t, "file.go", modulePath("", "/src/file.go"))
+	// Dotted path elements
+	assert.Equal(t, "gopkg.in/yaml.v2/decode.go", modulePath("gopkg.in/yaml.v2.(*parser).parse", "/mod/gopkg.in/yaml.v2@v2.4.0/decode.go"))
+	assert.Equal(t, "gopkg.in/yaml.v2/yaml.go", modulePath("gopkg.in/yaml.v2.Unmarshal.func1", "/vendor/gopkg.in/yaml.v2/yaml.go"))
+	assert.Equal(t, "pkg.v1/conn.go", modulePath("example.com/app/pkg.v1.Open", "/src/app/pkg.v1/conn.go"))
+}
+
+func TestFrameOf(t *testing.T) {
+	pc := callerConfig{}.callerPC(0)
+	f := frameOf(pc)
+	assert.Equal(t, "caller_test.go", path.Base(f.file))
+	assert.Equal(t, "github.com/kanmu/xlog.TestFrameOf", f.function)
+	// Cached
+	assert.True(t, f == frameOf(pc))
+}
+
+func TestDisableCaller(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, DisableCaller: true})
+	l.Info("test")
+	_, found := o.get()[KeyFile]
+	assert.False(t, found)
+	l.InfoEvent().Msg("test")
+	_, found = o.get()[KeyFile]
+	assert.False(t, found)
+}
+
+func TestCallerModulePath(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, CallerModulePath: true, CallerFunc: true})
+	l.Info("test")
+	last := o.get()
+	assert.Regexp(t, `^caller_test\.go:\d+$`, last[KeyFile])
+	assert.Equal(t, "xlog.TestCallerModulePath", last[KeyFunc])
+	l.InfoEvent().Msg("test")
+	last = o.get()
+	assert.Regexp(t, `^caller_test\.go:\d+$`, last[KeyFile])
+	assert.Equal(t, "xlog.TestCallerModulePath", last[KeyFunc])
+}
+
+func logHelper(l Logger) {
+	l.AddCallerSkip(1).Info("test")
+	l.AddCallerSkip(1).InfoEvent().Msg("test")
+}
+
+func TestAddCallerSkip(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, CallerFunc: true, Fields: xlog.F{"foo": "bar"}})
+	logHelper(l)
+	last := o.get()
+	assert.Equal(t, "xlog.TestAddCallerSkip", last[KeyFunc])
+	assert.Equal(t, "bar", last["foo"])
+	last = o.get()
+	assert.Equal(t, "xlog.TestAddCallerSkip", last[KeyFunc])
+
+	l.AddCallerSkip(1).AddCallerSkip(-1).Info("test")
+	assert.Equal(t, "xlog.TestAddCallerSkip", o.get()[KeyFunc])
+
+	l.Info("test")
+	assert.Equal(t, "xlog.TestAddCallerSkip", o.get()[KeyFunc])
+}
@@ -0,0 +1,161 @@
+package xlog
+
+import (
+	"github.com/rs/xlog"
+)
+
+// ConflictPolicy defines how a message field set with the same key as a logger
+// field is handled. Fields set with the same key as the ones set by the logger
+// (time, level, message, file, func and stack) are always moved under the
+// KeyFields namespace.
+type ConflictPolicy int
+
+// Conflict policies
+const (
+	// PreferContext keeps the logger field and drops the message field.
+	PreferContext ConflictPolicy = iota
+	// PreferMessage keeps the message field and drops the logger field.
+	PreferMessage
+	// RenameConflicts keeps the logger field and moves the message field under
+	// the KeyFields namespace (i.e.: fields.id).
+	RenameConflicts
+)
+
+// addFields sets the message and logger fields in data following the conflict
+// policy of the logger. The message fields renamed by the RenameConflicts policy
+// are returned.
+func (l *logger) addFields(data, fields, lfields map[string]interface{}) (renamed Namespace) {
+	for k, v := range fields {
+		data[k] = v
+	}
+	for k, v := range lfields {
+		mv, found := data[k]
+		if !found {
+			data[k] = v
+			continue
+		}
+		if ns, ok := v.(Namespace); ok {
+			if mns, ok := mv.(Namespace); ok {
+				// Namespaces are merged with the logger fields taking precedence
+				m := make(Namespace, len(mns)+len(ns))
+				mergeInto(m, mns)
+				mergeInto(m, ns)
+				data[k] = m
+				continue
+			}
+		}
+		l.warnConflict(k)
+		switch l.conflict {
+		case PreferMessage:
+			continue
+		case RenameConflicts:
+			renamed = rename(renamed, k, mv)
+		}
+		data[k] = v
+	}
+	return renamed
+}
+
+// setReserved sets the field k set by the logger in data. If a message or
+// logger field already uses this key, it is moved to the renamed fields.
+func (l *logger) setReserved(data map[string]interface{}, renamed Namespace, k string, v interface{}) Namespace {
+	if old, found := data[k]; found {
+		l.warnConflict(k)
+		renamed = rename(renamed, k, old)
+	}
+	data[k] = v
+	return renamed
+}
+
+// rename adds the field k to renamed, allocating it if nil.
+func rename(renamed Namespace, k string, v interface{}) Namespace {
+	if renamed == nil {
+		renamed = Namespace{}
+	}
+	renamed[k] = v
+	return renamed
+}
+
+// setRenamed sets the renamed fields in the key namespace of data. A field
+// already using key which is not a Namespace is moved into it too (i.e.:
+// fields.fields) unless a renamed field uses this key.
+func setRenamed(data map[string]interface{}, key string, renamed Namespace) {
+	if renamed == nil {
+		return
+	}
+	switch v := data[key].(type) {
+	case nil:
+	case Namespace:
+		m := make(Namespace, len(v)+len(renamed))
+		mergeInto(m, v)
+		mergeInto(m, renamed)
+		renamed = m
+	default:
+		if _, found := renamed[key]; !found {
+			renamed[key] = v
+		}
+	}
+	data[key] = renamed
+}
+
+// warnConflict reports a field conflict if the logger is configured to.
+func (l *logger) warnConflict(k string) {
+	if l.warnConflicts {
+		critialLogger.Printf("field conflict on key %q", k)
+	}
+}
+
+// hasConflicts returns true if an event field or a logger field may conflict
+// with another one or with a field set by the logger.
+func (e *Event) hasConflicts() bool {
+	for i := range e.fields {
+		k := e.fields[i].Key
+		if e.keys.isReserved(k) {
+			return true
+		}
+		if _, found := e.ctx[k]; found {
+			return true
+		}
+	}
+	for k := range e.ctx {
+		if e.keys.isReserved(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveConflicts applies the conflict policy of l to the event fields and the
+// logger fields. Both are merged in the event's logger fields. If hasStack is
+// true, the stack field is protected as the event will have it.
+func (e *Event) resolveConflicts(l *logger, hasStack bool) {
+	fields := make(xlog.F, len(e.fields))
+	for _, f := range e.fields {
+		fields[f.Key] = f.Value()
+	}
+	ctx := make(xlog.F, len(fields)+len(e.ctx))
+	renamed := l.addFields(ctx, fields, e.ctx)
+	reserved := []string{e.keys.Time, e.keys.Level, e.keys.Message}
+	if e.pc != 0 {
+		reserved = append(reserved, e.keys.File)
+		if e.caller.funcName {
+			reserved = append(reserved, e.keys.Func)
+		}
+	}
+	if hasStack {
+		reserved = append(reserved, e.keys.Stack)
+	}
+	for _, k := range reserved {
+		if v, found := ctx[k]; found {
+			delete(ctx, k)
+			l.warnConflict(k)
+			renamed = rename(renamed, k, v)
+		}
+	}
+	setRenamed(ctx, e.keys.Fields, renamed)
+	e.ctx = ctx
+	for i := range e.fields {
+		e.fields[i] = Field{}
+	}
+	e.fields = e.fields[:0]
+}
@@ -0,0 +1,110 @@
+package xlog
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservedKeys(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, Fields: xlog.F{"level": "ctx"}})
+	l.Info("test", xlog.F{"message": "field", "time": 1, "file": "field"})
+	last := o.get()
+	assert.Equal(t, "info", last[KeyLevel])
+	assert.Equal(t, "test", last[KeyMessage])
+	assert.Contains(t, last[KeyFile], "conflict_test.go")
+	assert.Equal(t, Namespace{"level": "ctx", "message": "field", "time": 1, "file": "field"}, last[KeyFields])
+}
+
+func TestReservedKeysCallerDisabled(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, DisableCaller: true})
+	l.Info("test", xlog.F{"file": "field"})
+	last := o.get()
+	assert.Equal(t, "field", last[KeyFile])
+	assert.NotContains(t, last, KeyFields)
+}
+
+func TestFieldConflict(t *testing.T) {
+	cases := []struct {
+		policy ConflictPolicy
+		id     interface{}
+		fields interface{}
+	}{
+		{PreferContext, "ctx", nil},
+		{PreferMessage, "msg", nil},
+		{RenameConflicts, "ctx", Namespace{"id": "msg"}},
+	}
+	for _, c := range cases {
+		o := newTestOutput()
+		l := New(Config{Output: o, DisableCaller: true, FieldConflict: c.policy, Fields: xlog.F{"id": "ctx"}})
+		l.Info("test", xlog.F{"id": "msg"})
+		last := o.get()
+		assert.Equal(t, c.id, last["id"], "policy %d", c.policy)
+		assert.Equal(t, c.fields, last[KeyFields], "policy %d", c.policy)
+
+		l.InfoEvent().Str("id", "msg").Msg("test")
+		last = o.get()
+		assert.Equal(t, c.id, last["id"], "event policy %d", c.policy)
+		assert.Equal(t, c.fields, last[KeyFields], "event policy %d", c.policy)
+	}
+}
+
+func TestFieldConflictRenamedMerge(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, DisableCaller: true, FieldConflict: RenameConflicts,
+		Fields: xlog.F{"id": "ctx", KeyFields: Namespace{"a": 1}}})
+	l.Info("test", xlog.F{"id": "msg", KeyLevel: "msg"})
+	assert.Equal(t, Namespace{"a": 1, "id": "msg", "level": "msg"}, o.get()[KeyFields])
+}
+
+func TestFieldConflictRenamedNotNamespace(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, DisableCaller: true})
+	l.Info("test", xlog.F{KeyFields: "x", KeyLevel: "gold"})
+	last := o.get()
+	assert.Equal(t, "info", last[KeyLevel])
+	assert.Equal(t, Namespace{"fields": "x", "level": "gold"}, last[KeyFields])
+
+	l.InfoEvent().Str(KeyFields, "x").Str(KeyLevel, "gold").Msg("test")
+	last = o.get()
+	assert.Equal(t, "info", last[KeyLevel])
+	assert.Equal(t, Namespace{"fields": "x", "level": "gold"}, last[KeyFields])
+
+	l.Info("test", xlog.F{KeyFields: "x"})
+	assert.Equal(t, "x", o.get()[KeyFields])
+}
+
+func TestEventReservedKeys(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, DisableCaller: true, Fields: xlog.F{"message": "ctx"}})
+	l.InfoEvent().Str("level", "field").Int("id", 1).Msg("test")
+	last := o.get()
+	assert.Equal(t, "info", last[KeyLevel])
+	assert.Equal(t, "test", last[KeyMessage])
+	assert.Equal(t, int64(1), last["id"])
+	assert.Equal(t, Namespace{"message": "ctx", "level": "field"}, last[KeyFields])
+}
+
+func TestWarnConflicts(t *testing.T) {
+	buf := &bytes.Buffer{}
+	critialLoggerMux.Lock()
+	oldCritialLogger := critialLogger
+	critialLogger = log.New(buf, "", 0)
+	defer func() {
+		critialLogger = oldCritialLogger
+		critialLoggerMux.Unlock()
+	}()
+	o := newTestOutput()
+	l := New(Config{Output: o, DisableCaller: true, WarnConflicts: true, Fields: xlog.F{"id": 1}})
+	l.Info("test", xlog.F{"id": 2})
+	assert.Equal(t, "field conflict on key \"id\"\n", buf.String())
+
+	buf.Reset()
+	New(Config{Output: o, DisableCaller: true, Fields: xlog.F{"id": 1}}).Info("test", xlog.F{"id": 2})
+	assert.Equal(t, "", buf.String())
+}
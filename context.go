@@ -0,0 +1,141 @@
+package xlog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/xlog"
+)
+
+// ContextExtractor returns the fields to add to the messages sent with a context
+// by the Ctx methods of a logger, like InfoCtx. It returns nil if ctx has no
+// data to log.
+type ContextExtractor func(ctx context.Context) xlog.F
+
+// traceIDs are the ids of the trace and span stored in a context.
+type traceIDs struct {
+	trace string
+	span  string
+}
+
+// NewTraceContext returns a copy of the parent context storing the ids of the
+// current trace and span. They are sent with the messages logged with the Ctx
+// methods of a logger.
+func NewTraceContext(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, traceKey, traceIDs{trace: traceID, span: spanID})
+}
+
+// TraceFromContext returns the ids of the trace and span stored in ctx by
+// NewTraceContext if any.
+func TraceFromContext(ctx context.Context) (traceID, spanID string, ok bool) {
+	ids, ok := ctx.Value(traceKey).(traceIDs)
+	return ids.trace, ids.span, ok
+}
+
+// extractContext returns the fields to send with the messages logged with ctx:
+// the request id, the trace and span ids and the fields stored by WithFields,
+// followed by the fields returned by extractors.
+func extractContext(ctx context.Context, keys *FieldKeys, extractors []ContextExtractor) xlog.F {
+	if ctx == nil {
+		return nil
+	}
+	f := mergeFields(contextFields(ctx), nil, nil)
+	if id, ok := IDFromContext(ctx); ok {
+		f[keys.RequestID] = id
+	}
+	if traceID, spanID, ok := TraceFromContext(ctx); ok {
+		if traceID != "" {
+			f[keys.TraceID] = traceID
+		}
+		if spanID != "" {
+			f[keys.SpanID] = spanID
+		}
+	}
+	for _, extract := range extractors {
+		for k, v := range extract(ctx) {
+			f[k] = v
+		}
+	}
+	return f
+}
+
+// sendCtx sends a message with the fields extracted from ctx. They are handled
+// like logger fields, taking precedence over them.
+func (l *logger) sendCtx(ctx context.Context, level xlog.Level, calldepth int, msg string, fields map[string]interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	lfields := l.logFields()
+	if f := extractContext(ctx, l.keys, l.extractors); len(f) > 0 {
+		lfields = mergeFields(lfields, nil, f)
+	}
+	l.sendWith(level, calldepth+1, msg, fields, lfields)
+}
+
+// TraceCtx implements Logger interface
+func (l *logger) TraceCtx(ctx context.Context, v ...interface{}) {
+	if !l.Enabled(LevelTrace) {
+		return
+	}
+	f := extractFields(&v)
+	l.sendCtx(ctx, LevelTrace, 2, fmt.Sprint(v...), f)
+}
+
+// DebugCtx implements Logger interface
+func (l *logger) DebugCtx(ctx context.Context, v ...interface{}) {
+	if !l.Enabled(xlog.LevelDebug) {
+		return
+	}
+	f := extractFields(&v)
+	l.sendCtx(ctx, xlog.LevelDebug, 2, fmt.Sprint(v...), f)
+}
+
+// InfoCtx implements Logger interface
+func (l *logger) InfoCtx(ctx context.Context, v ...interface{}) {
+	if !l.Enabled(xlog.LevelInfo) {
+		return
+	}
+	f := extractFields(&v)
+	l.sendCtx(ctx, xlog.LevelInfo, 2, fmt.Sprint(v...), f)
+}
+
+// WarnCtx implements Logger interface
+func (l *logger) WarnCtx(ctx context.Context, v ...interface{}) {
+	if !l.Enabled(xlog.LevelWarn) {
+		return
+	}
+	f := extractFields(&v)
+	l.sendCtx(ctx, xlog.LevelWarn, 2, fmt.Sprint(v...), f)
+}
+
+// ErrorCtx implements Logger interface
+func (l *logger) ErrorCtx(ctx context.Context, v ...interface{}) {
+	if !l.Enabled(xlog.LevelError) {
+		return
+	}
+	f := extractFields(&v)
+	l.sendCtx(ctx, xlog.LevelError, 2, fmt.Sprint(v...), f)
+}
+
+// FatalCtx implements Logger interface
+func (l *logger) FatalCtx(ctx context.Context, v ...interface{}) {
+	if l.Enabled(xlog.LevelFatal) {
+		f := extractFields(&v)
+		l.sendCtx(ctx, xlog.LevelFatal, 2, fmt.Sprint(v...), f)
+	}
+	if o, ok := l.output.(*OutputChannel); ok {
+		o.Close()
+	}
+	exit1()
+}
+
+// PanicCtx implements Logger interface
+func (l *logger) PanicCtx(ctx context.Context, v ...interface{}) {
+	f := extractFields(&v)
+	msg := fmt.Sprint(v...)
+	l.sendCtx(ctx, LevelPanic, 2, msg, f)
+	if o, ok := l.output.(*OutputChannel); ok {
+		o.Flush()
+	}
+	panic(msg)
+}
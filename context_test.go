@@ -0,0 +1,113 @@
+package xlog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type tenantKey struct{}
+
+func tenantExtractor(ctx context.Context) xlog.F {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return xlog.F{"tenant": tenant}
+	}
+	return nil
+}
+
+func TestTraceFromContext(t *testing.T) {
+	_, _, ok := TraceFromContext(context.Background())
+	assert.False(t, ok)
+	traceID, spanID, ok := TraceFromContext(NewTraceContext(context.Background(), "t1", "s1"))
+	assert.True(t, ok)
+	assert.Equal(t, "t1", traceID)
+	assert.Equal(t, "s1", spanID)
+}
+
+func TestExtractContext(t *testing.T) {
+	keys := DefaultFieldKeys()
+	assert.Nil(t, extractContext(nil, &keys, nil))
+	assert.Equal(t, xlog.F{}, extractContext(context.Background(), &keys, nil))
+
+	id := xid.New()
+	ctx := context.WithValue(context.Background(), idKey, id)
+	ctx = NewTraceContext(ctx, "t1", "")
+	ctx = WithFields(ctx, xlog.F{"a": 1, "tenant": "fields"})
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+	assert.Equal(t, xlog.F{"req_id": id, "trace_id": "t1", "a": 1, "tenant": "acme"},
+		extractContext(ctx, &keys, []ContextExtractor{tenantExtractor}))
+
+	keys = FieldKeys{RequestID: "request", TraceID: "trace", SpanID: "span"}.withDefaults()
+	ctx = NewTraceContext(ctx, "t2", "s2")
+	assert.Equal(t, xlog.F{"request": id, "trace": "t2", "span": "s2", "a": 1, "tenant": "fields"},
+		extractContext(ctx, &keys, nil))
+}
+
+func TestLoggerCtx(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{
+		Output:            o,
+		Level:             LevelTrace,
+		Fields:            xlog.F{"a": 0, "b": 0},
+		ContextExtractors: []ContextExtractor{tenantExtractor},
+	})
+	ctx := WithFields(context.Background(), xlog.F{"a": 1})
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+	for level, log := range map[string]func(context.Context, ...interface{}){
+		"trace": l.TraceCtx,
+		"debug": l.DebugCtx,
+		"info":  l.InfoCtx,
+		"warn":  l.WarnCtx,
+		"error": l.ErrorCtx,
+	} {
+		log(ctx, "test", xlog.F{"c": 2})
+		last := o.get()
+		assert.Equal(t, level, last[KeyLevel])
+		assert.Contains(t, last[KeyFile], "context_test.go:", level)
+		assert.Equal(t, 1, last["a"])
+		assert.Equal(t, 0, last["b"])
+		assert.Equal(t, 2, last["c"])
+		assert.Equal(t, "acme", last["tenant"])
+	}
+
+	// Context fields are not reported as conflicts when the logger was
+	// obtained from the same context
+	l = New(Config{Output: o, FieldConflict: RenameConflicts})
+	FromContext(WithFields(NewContext(ctx, l), xlog.F{"b": 1})).InfoCtx(ctx, "test")
+	last := o.get()
+	assert.Equal(t, 1, last["a"])
+	assert.Equal(t, 1, last["b"])
+	assert.NotContains(t, last, KeyFields)
+
+	// Disabled levels are not extracted
+	l = New(Config{Output: o, Level: xlog.LevelError, ContextExtractors: []ContextExtractor{
+		func(ctx context.Context) xlog.F {
+			t.Error("extractor called for a disabled level")
+			return nil
+		},
+	}})
+	l.InfoCtx(ctx, "test")
+	assert.True(t, o.empty())
+}
+
+func TestLoggerFatalPanicCtx(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o})
+	ctx := WithFields(context.Background(), xlog.F{"a": 1})
+	oldExit := exit1
+	exited := false
+	exit1 = func() { exited = true }
+	defer func() { exit1 = oldExit }()
+	l.FatalCtx(ctx, "test")
+	assert.True(t, exited)
+	last := o.get()
+	assert.Equal(t, "fatal", last[KeyLevel])
+	assert.Equal(t, 1, last["a"])
+	assert.PanicsWithValue(t, "test", func() { l.PanicCtx(ctx, "test") })
+	last = o.get()
+	assert.Equal(t, "panic", last[KeyLevel])
+	assert.Equal(t, 1, last["a"])
+}
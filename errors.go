@@ -0,0 +1,172 @@
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rs/xlog"
+)
+
+// LogFielder is implemented by errors providing structured fields to log along
+// with their message, like a decline code or a status.
+type LogFielder interface {
+	LogFields() xlog.F
+}
+
+// Keys of the object used to represent errors by structured outputs.
+const (
+	errorMessageKey = "message"
+	errorTypesKey   = "types"
+)
+
+// maxErrorDepth limits the walk of error chains.
+const maxErrorDepth = 32
+
+// walkErrors calls fn for err and all the errors it wraps, depth first.
+// Both Unwrap() error and Unwrap() []error (as returned by errors.Join) are
+// followed.
+func walkErrors(err error, fn func(err error)) {
+	var walk func(err error, depth int)
+	walk = func(err error, depth int) {
+		if err == nil || depth > maxErrorDepth {
+			return
+		}
+		fn(err)
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, err := range e.Unwrap() {
+				walk(err, depth+1)
+			}
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap(), depth+1)
+		}
+	}
+	walk(err, 0)
+}
+
+// errorObject returns the structured representation of an error: its message,
+// the type names of the error and of all the errors it wraps, and the fields
+// provided by the errors of the chain implementing LogFielder. Fields of outer
+// errors take precedence over the ones of the errors they wrap.
+//
+// Errors implementing json.Marshaler are returned as is.
+func errorObject(err error) interface{} {
+	if _, ok := err.(json.Marshaler); ok {
+		return err
+	}
+	obj, _ := errorDetails(err)
+	obj[errorMessageKey] = err.Error()
+	return obj
+}
+
+// errorDetails returns the type names and LogFielder fields of an error chain
+// in a map. The message and types keys are reserved and cannot be set by
+// LogFielder errors. The returned bool is true if the error wraps other errors or has
+// fields, false if the details only contain the type of err.
+func errorDetails(err error) (xlog.F, bool) {
+	types := []string{}
+	fields := []xlog.F{}
+	walkErrors(err, func(err error) {
+		types = append(types, reflect.TypeOf(err).String())
+		if lf, ok := err.(LogFielder); ok {
+			fields = append(fields, lf.LogFields())
+		}
+	})
+	obj := xlog.F{}
+	for i := len(fields) - 1; i >= 0; i-- {
+		for k, v := range fields[i] {
+			obj[k] = v
+		}
+	}
+	delete(obj, errorMessageKey)
+	obj[errorTypesKey] = types
+	return obj, len(types) > 1 || len(fields) > 0
+}
+
+// withErrorObjects returns fields with all the error values, including the ones
+// of namespaces and nested maps, replaced by their structured representation. The fields map is
+// copied if it contains errors so outputs sharing the same message are not
+// affected.
+func withErrorObjects(fields map[string]interface{}) map[string]interface{} {
+	f, _ := replaceErrors(fields)
+	return f
+}
+
+// replaceErrors implements withErrorObjects. The returned bool is false if
+// fields contains no error and is returned as is.
+func replaceErrors(fields map[string]interface{}) (map[string]interface{}, bool) {
+	var f map[string]interface{}
+	for k, v := range fields {
+		switch t := v.(type) {
+		case error:
+			v = errorObject(t)
+		case Namespace:
+			ns, ok := replaceErrors(t)
+			if !ok {
+				continue
+			}
+			v = Namespace(ns)
+		case xlog.F:
+			m, ok := replaceErrors(t)
+			if !ok {
+				continue
+			}
+			v = xlog.F(m)
+		case map[string]interface{}:
+			m, ok := replaceErrors(t)
+			if !ok {
+				continue
+			}
+			v = m
+		default:
+			continue
+		}
+		if f == nil {
+			f = make(map[string]interface{}, len(fields))
+			for k, v := range fields {
+				f[k] = v
+			}
+		}
+		f[k] = v
+	}
+	if f == nil {
+		return fields, false
+	}
+	return f, true
+}
+
+// writeErrorDetails writes the details of an error chain as dotted logfmt keys
+// prefixed by the key of the error (i.e.: err.types=… err.code=…). Nothing is
+// written for errors wrapping no other error and having no fields. If keyColor
+// is true, keys are colored as in the console output.
+func writeErrorDetails(buf *bytes.Buffer, key string, err error, keyColor bool) error {
+	details, ok := errorDetails(err)
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(details))
+	for k := range details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf.WriteByte(' ')
+		if keyColor {
+			colorPrint(buf, key+"."+k, ColorGreen)
+		} else {
+			buf.WriteString(key + "." + k)
+		}
+		buf.WriteByte('=')
+		v := details[k]
+		if k == errorTypesKey {
+			v = strings.Join(v.([]string), ",")
+		}
+		if err := writeValue(buf, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
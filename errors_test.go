@@ -0,0 +1,199 @@
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type declineError struct {
+	code string
+	err  error
+}
+
+func (e *declineError) Error() string { return "card declined: " + e.code }
+
+func (e *declineError) Unwrap() error { return e.err }
+
+func (e *declineError) LogFields() xlog.F {
+	return xlog.F{"decline_code": e.code, "message": "not overridable"}
+}
+
+// wrapError and joinError mimic the errors returned by fmt.Errorf with %w and
+// errors.Join, not available with Go 1.7.
+type wrapError struct {
+	msg string
+	err error
+}
+
+func wrap(msg string, err error) error { return &wrapError{msg: msg, err: err} }
+
+func (e *wrapError) Error() string { return e.msg + ": " + e.err.Error() }
+
+func (e *wrapError) Unwrap() error { return e.err }
+
+type joinError struct {
+	errs []error
+}
+
+func join(errs ...error) error { return &joinError{errs: errs} }
+
+func (e *joinError) Error() string {
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e *joinError) Unwrap() []error { return e.errs }
+
+type jsonError struct{}
+
+func (jsonError) Error() string { return "json error" }
+
+func (jsonError) MarshalJSON() ([]byte, error) { return []byte(`"custom"`), nil }
+
+func TestErrorObject(t *testing.T) {
+	err := errors.New("some error")
+	assert.Equal(t, xlog.F{
+		"message": "some error",
+		"types":   []string{"*errors.errorString"},
+	}, errorObject(err))
+
+	derr := &declineError{code: "insufficient_funds", err: err}
+	werr := wrap("charge failed", derr)
+	assert.Equal(t, xlog.F{
+		"message":      "charge failed: card declined: insufficient_funds",
+		"types":        []string{"*xlog.wrapError", "*xlog.declineError", "*errors.errorString"},
+		"decline_code": "insufficient_funds",
+	}, errorObject(werr))
+
+	assert.Equal(t, jsonError{}, errorObject(jsonError{}))
+}
+
+func TestErrorObjectFieldsPrecedence(t *testing.T) {
+	inner := &declineError{code: "inner"}
+	outer := &declineError{code: "outer", err: inner}
+	obj := errorObject(outer).(xlog.F)
+	assert.Equal(t, "outer", obj["decline_code"])
+	assert.Equal(t, []string{"*xlog.declineError", "*xlog.declineError"}, obj["types"])
+}
+
+func TestErrorObjectJoin(t *testing.T) {
+	err := join(errors.New("a"), &declineError{code: "b"})
+	obj := errorObject(err).(xlog.F)
+	assert.Equal(t, []string{"*xlog.joinError", "*errors.errorString", "*xlog.declineError"}, obj["types"])
+	assert.Equal(t, "b", obj["decline_code"])
+}
+
+func TestWalkErrorsDepth(t *testing.T) {
+	err := errors.New("root")
+	for i := 0; i < 2*maxErrorDepth; i++ {
+		err = wrap("wrap", err)
+	}
+	n := 0
+	walkErrors(err, func(error) { n++ })
+	assert.Equal(t, maxErrorDepth+1, n)
+}
+
+func TestWithErrorObjects(t *testing.T) {
+	fields := map[string]interface{}{"foo": "bar"}
+	assert.Equal(t, fields, withErrorObjects(fields))
+
+	err := errors.New("some error")
+	fields = map[string]interface{}{"foo": "bar", "err": err}
+	f := withErrorObjects(fields)
+	assert.Equal(t, err, fields["err"])
+	assert.Equal(t, "some error", f["err"].(xlog.F)["message"])
+	assert.Equal(t, "bar", f["foo"])
+
+	fields = map[string]interface{}{
+		"ns":   Namespace{"err": err},
+		"f":    xlog.F{"err": err},
+		"map":  map[string]interface{}{"sub": xlog.F{"err": err}},
+		"none": xlog.F{"foo": "bar"},
+	}
+	f = withErrorObjects(fields)
+	assert.Equal(t, "some error", f["ns"].(Namespace)["err"].(xlog.F)["message"])
+	assert.Equal(t, "some error", f["f"].(xlog.F)["err"].(xlog.F)["message"])
+	assert.Equal(t, "some error", f["map"].(map[string]interface{})["sub"].(xlog.F)["err"].(xlog.F)["message"])
+	assert.Equal(t, xlog.F{"foo": "bar"}, f["none"])
+	assert.Equal(t, err, fields["f"].(xlog.F)["err"])
+}
+
+func TestLogstashOutputNestedError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o := NewLogstashOutput(buf)
+	err := errors.New("some error")
+	assert.NoError(t, o.Write(map[string]interface{}{"ns": Namespace{"err": err}, "f": xlog.F{"err": err}}))
+	v := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &v))
+	obj := map[string]interface{}{"err": map[string]interface{}{
+		"message": "some error",
+		"types":   []interface{}{"*errors.errorString"},
+	}}
+	assert.Equal(t, obj, v["ns"])
+	assert.Equal(t, obj, v["f"])
+}
+
+func TestJSONOutputNestedError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o := NewJSONOutput(buf)
+	assert.NoError(t, o.Write(map[string]interface{}{"f": xlog.F{"err": errors.New("some error")}}))
+	assert.Equal(t, `{"f":{"err":{"message":"some error","types":["*errors.errorString"]}}}`+"\n", buf.String())
+
+	buf.Reset()
+	l := New(Config{Output: o, DisableCaller: true})
+	l.InfoEvent().Interface("f", xlog.F{"err": errors.New("some error")}).Msg("test")
+	assert.Contains(t, buf.String(), `"f":{"err":{"message":"some error","types":["*errors.errorString"]}}`)
+}
+
+func TestJSONOutputError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o := NewJSONOutput(buf)
+	err := wrap("charge failed", &declineError{code: "expired_card"})
+	assert.NoError(t, o.Write(map[string]interface{}{"err": err, "json": jsonError{}}))
+	assert.Equal(t, `{"err":{"decline_code":"expired_card","message":"charge failed: card declined: expired_card","types":["*xlog.wrapError","*xlog.declineError"]},"json":"custom"}`+"\n", buf.String())
+}
+
+func TestLogstashOutputError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o := NewLogstashOutput(buf)
+	assert.NoError(t, o.Write(map[string]interface{}{"err": errors.New("some error")}))
+	v := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &v))
+	assert.Equal(t, map[string]interface{}{
+		"message": "some error",
+		"types":   []interface{}{"*errors.errorString"},
+	}, v["err"])
+}
+
+func TestLogfmtOutputError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o := NewLogfmtOutput(buf)
+	err := wrap("charge failed", &declineError{code: "expired_card"})
+	assert.NoError(t, o.Write(map[string]interface{}{"err": err, "plain": errors.New("plain")}))
+	assert.Equal(t, "level=null message=null time=null err=\"charge failed: card declined: expired_card\" err.decline_code=expired_card err.types=*xlog.wrapError,*xlog.declineError plain=plain\n", buf.String())
+}
+
+func TestConsoleOutputError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o := consoleOutput{w: buf, keys: DefaultFieldKeys()}
+	err := &declineError{code: "expired_card", err: errors.New("root")}
+	assert.NoError(t, o.Write(map[string]interface{}{"message": "test", "err": err}))
+	assert.Equal(t, "test \x1b[32merr\x1b[0m=\"card declined: expired_card\" \x1b[32merr.decline_code\x1b[0m=expired_card \x1b[32merr.types\x1b[0m=*xlog.declineError,*errors.errorString\n", buf.String())
+}
+
+func TestEventError(t *testing.T) {
+	e := newEncoderTestEvent()
+	e.fields = append(e.fields, Field{Key: "declined", Type: InterfaceType, Interface: &declineError{code: "c"}})
+	for _, written := range assertWriteEvent(t, e) {
+		assert.Contains(t, written, "decline_code")
+	}
+}
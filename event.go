@@ -0,0 +1,433 @@
+package xlog
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/xlog"
+)
+
+// FieldType defines the type of the value stored in a Field.
+type FieldType uint8
+
+// Field types
+const (
+	// StringType is a string stored in Field.String.
+	StringType FieldType = iota
+	// Int64Type is an int64 stored in Field.Integer.
+	Int64Type
+	// Uint64Type is an uint64 stored as is in Field.Integer.
+	Uint64Type
+	// Float64Type is a float64 stored as its IEEE 754 binary representation in
+	// Field.Integer.
+	Float64Type
+	// BoolType is a bool stored as 1 or 0 in Field.Integer.
+	BoolType
+	// TimeType is a time stored in Field.Time.
+	TimeType
+	// DurationType is a time.Duration stored in Field.Integer.
+	DurationType
+	// ErrorType is an error stored in Field.Interface.
+	ErrorType
+	// InterfaceType is any value stored in Field.Interface.
+	InterfaceType
+	// CallerType is the location of the log call with the file path stored in
+	// Field.String and the line in Field.Integer.
+	CallerType
+)
+
+// Field is a typed key/value pair of an Event. Only the struct member defined by
+// the Type is set.
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	String    string
+	Time      time.Time
+	Interface interface{}
+}
+
+// Value returns the value of the field in the form used by xlog.Output.
+func (f Field) Value() interface{} {
+	switch f.Type {
+	case StringType:
+		return f.String
+	case Int64Type:
+		return f.Integer
+	case Uint64Type:
+		return uint64(f.Integer)
+	case Float64Type:
+		return math.Float64frombits(uint64(f.Integer))
+	case BoolType:
+		return f.Integer == 1
+	case TimeType:
+		return f.Time
+	case DurationType:
+		return time.Duration(f.Integer)
+	case CallerType:
+		return path.Base(f.String) + ":" + strconv.FormatInt(f.Integer, 10)
+	}
+	return f.Interface
+}
+
+// EventOutput is implemented by outputs able to encode the typed fields of an
+// Event directly. Outputs not implementing this interface receive the event
+// converted to a map thru their Write method.
+//
+// The event is reused once WriteEvent returns and must not be retained.
+type EventOutput interface {
+	WriteEvent(e *Event) error
+}
+
+// Event is a log message with typed fields. Events are obtained thru the
+// DebugEvent, InfoEvent, … methods of a Logger and sent with Msg or Msgf.
+//
+// When the level is disabled, a nil *Event is returned on which all methods are
+// no-op. Events are pooled and must not be used once sent.
+type Event struct {
+	l      *logger
+	level  xlog.Level
+	time   time.Time
+	msg    string
+	pc     uintptr
+	caller callerConfig
+	keys   *FieldKeys
+	ctx    xlog.F
+	fields []Field
+	sorted []Field
+}
+
+var eventPool = &sync.Pool{
+	New: func() interface{} {
+		return &Event{fields: make([]Field, 0, 8)}
+	},
+}
+
+// maxPooledFields is the capacity above which an event is not put back in the
+// pool so a single large message does not keep memory forever.
+const maxPooledFields = 256
+
+func getEvent() *Event {
+	return eventPool.Get().(*Event)
+}
+
+func putEvent(e *Event) {
+	if cap(e.fields) > maxPooledFields || cap(e.sorted) > maxPooledFields {
+		return
+	}
+	// Release references held by the fields
+	for i := range e.fields {
+		e.fields[i] = Field{}
+	}
+	for i := range e.sorted {
+		e.sorted[i] = Field{}
+	}
+	e.fields = e.fields[:0]
+	e.sorted = e.sorted[:0]
+	e.l = nil
+	e.msg = ""
+	e.pc = 0
+	e.caller = callerConfig{}
+	e.keys = nil
+	e.ctx = nil
+	eventPool.Put(e)
+}
+
+// newEvent returns a new event for the given level or nil if the level is disabled
+func (l *logger) newEvent(level xlog.Level) *Event {
+	if !l.Enabled(level) {
+		return nil
+	}
+	e := getEvent()
+	e.l = l
+	e.level = level
+	e.keys = l.keys
+	e.ctx = l.logFields()
+	return e
+}
+
+// writeEvent sends the event to the logger's output and takes ownership of e.
+// If the logger has hooks, the event is converted to a map so hooks can modify
+// its fields.
+func (l *logger) writeEvent(e *Event) {
+	var err error
+	if len(l.hooks) > 0 {
+		level, msg, fields := e.level, e.msg, e.Fields()
+		putEvent(e)
+		if !l.runHooks(level, msg, fields) {
+			return
+		}
+		err = l.writeFields(level, fields)
+	} else if oc, ok := l.output.(*OutputChannel); ok {
+		err = oc.writeEvent(e)
+	} else {
+		err = writeEvent(l.output, e)
+		putEvent(e)
+	}
+	if err != nil && err != ErrBufferFull {
+		critialLogger.Print("send error: ", err.Error())
+	}
+}
+
+// writeEvent writes the event to o, converting it to a map if o does not
+// implement EventOutput.
+func writeEvent(o xlog.Output, e *Event) error {
+	if eo, ok := o.(EventOutput); ok {
+		return eo.WriteEvent(e)
+	}
+	return o.Write(e.Fields())
+}
+
+// Level returns the level of the event.
+func (e *Event) Level() xlog.Level {
+	return e.level
+}
+
+// Message returns the message of the event.
+func (e *Event) Message() string {
+	return e.msg
+}
+
+// Str adds the field key with val as a string to the event.
+func (e *Event) Str(key, val string) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: key, Type: StringType, String: val})
+	return e
+}
+
+// Int adds the field key with val as an int to the event.
+func (e *Event) Int(key string, val int) *Event {
+	return e.Int64(key, int64(val))
+}
+
+// Int64 adds the field key with val as an int64 to the event.
+func (e *Event) Int64(key string, val int64) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: key, Type: Int64Type, Integer: val})
+	return e
+}
+
+// Uint64 adds the field key with val as an uint64 to the event.
+func (e *Event) Uint64(key string, val uint64) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: key, Type: Uint64Type, Integer: int64(val)})
+	return e
+}
+
+// Float64 adds the field key with val as a float64 to the event.
+func (e *Event) Float64(key string, val float64) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: key, Type: Float64Type, Integer: int64(math.Float64bits(val))})
+	return e
+}
+
+// Bool adds the field key with val as a bool to the event.
+func (e *Event) Bool(key string, val bool) *Event {
+	if e == nil {
+		return e
+	}
+	var i int64
+	if val {
+		i = 1
+	}
+	e.fields = append(e.fields, Field{Key: key, Type: BoolType, Integer: i})
+	return e
+}
+
+// Time adds the field key with val as a time to the event.
+func (e *Event) Time(key string, val time.Time) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: key, Type: TimeType, Time: val})
+	return e
+}
+
+// Dur adds the field key with val as a duration to the event.
+func (e *Event) Dur(key string, val time.Duration) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: key, Type: DurationType, Integer: int64(val)})
+	return e
+}
+
+// Err adds the err to the event using the error field name of the logger. Nothing is added
+// if err is nil.
+func (e *Event) Err(err error) *Event {
+	if e == nil || err == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: e.fieldKeys().Error, Type: ErrorType, Interface: err})
+	return e
+}
+
+// Interface adds the field key with val to the event. The value is serialized
+// by the output the same way as values passed in a xlog.F.
+func (e *Event) Interface(key string, val interface{}) *Event {
+	if e == nil {
+		return e
+	}
+	e.fields = append(e.fields, Field{Key: key, Type: InterfaceType, Interface: val})
+	return e
+}
+
+// Msg sends the event with the given message. If the event level is fatal, the
+// program exits after the message is sent. If it is panic, panic is called with
+// the message.
+func (e *Event) Msg(msg string) {
+	if e == nil {
+		return
+	}
+	e.msg = msg
+	e.send(2)
+}
+
+// Msgf sends the event with a message formatted with fmt.Sprintf. The message is
+// only formatted if the event level is enabled.
+func (e *Event) Msgf(format string, v ...interface{}) {
+	if e == nil {
+		return
+	}
+	e.msg = fmt.Sprintf(format, v...)
+	e.send(2)
+}
+
+func (e *Event) send(calldepth int) {
+	l, level, msg := e.l, e.level, e.msg
+	if l == nil {
+		putEvent(e)
+	} else {
+		e.time = l.Now()
+		// Only store the program counter as runtime.Caller allocates. The file
+		// and line are resolved when the event is encoded.
+		e.caller = l.caller
+		e.pc = l.caller.callerPC(calldepth)
+		var stack string
+		if l.stackEnabled(level) {
+			if stack = e.errorStack(); stack == "" {
+				stack = captureStack(calldepth + l.caller.skip)
+			}
+		}
+		if len(l.group) > 0 && len(e.fields) > 0 {
+			e.groupFields(l.group)
+		}
+		if e.hasConflicts() {
+			e.resolveConflicts(l, stack != "")
+		}
+		if stack != "" {
+			e.fields = append(e.fields, Field{Key: l.keys.Stack, Type: StringType, String: stack})
+		}
+		e.resolveLazy(!l.deferLazy())
+		if l.snapshot {
+			e.snapshot()
+		}
+		l.writeEvent(e)
+	}
+	if level == xlog.LevelFatal {
+		if l != nil {
+			if o, ok := l.output.(*OutputChannel); ok {
+				o.Close()
+			}
+		}
+		exit1()
+	}
+	if level == LevelPanic {
+		if l != nil {
+			if o, ok := l.output.(*OutputChannel); ok {
+				o.Flush()
+			}
+		}
+		panic(msg)
+	}
+}
+
+// errorStack returns the stack trace carried by the first error found in the
+// event fields or an empty string.
+func (e *Event) errorStack() string {
+	for _, f := range e.fields {
+		if err, ok := f.Interface.(error); ok {
+			if s := errorStack(err); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// fieldKeys returns the field keys of the event's logger or the default ones
+// if the event has no logger.
+func (e *Event) fieldKeys() *FieldKeys {
+	if e.keys == nil {
+		return defaultKeys()
+	}
+	return e.keys
+}
+
+// Fields returns the event in the map form used by xlog.Output.
+func (e *Event) Fields() map[string]interface{} {
+	fields := e.sortedFields()
+	m := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		m[f.Key] = f.Value()
+	}
+	return m
+}
+
+// AppendFields appends all the fields of the event to dst, including the time,
+// level, message and file fields, sorted by key. When a key is set more than once,
+// logger fields take precedence over the event fields which take precedence over
+// the message fields, the same way as for messages sent with Info, Error, …
+func (e *Event) AppendFields(dst []Field) []Field {
+	start := len(dst)
+	keys := e.fieldKeys()
+	dst = append(dst,
+		Field{Key: keys.Time, Type: TimeType, Time: e.time},
+		Field{Key: keys.Level, Type: StringType, String: levelName(e.level)},
+		Field{Key: keys.Message, Type: StringType, String: e.msg},
+	)
+	dst = e.caller.appendCaller(dst, keys, e.pc)
+	dst = append(dst, e.fields...)
+	for k, v := range e.ctx {
+		dst = append(dst, Field{Key: k, Type: InterfaceType, Interface: v})
+	}
+	// Stable insertion sort so the precedence order is kept for duplicated keys.
+	// The number of fields is expected to be small.
+	f := dst[start:]
+	for i := 1; i < len(f); i++ {
+		for j := i; j > 0 && f[j].Key < f[j-1].Key; j-- {
+			f[j], f[j-1] = f[j-1], f[j]
+		}
+	}
+	// Remove duplicates keeping the last occurrence
+	n := 0
+	for i := range f {
+		if i+1 < len(f) && f[i+1].Key == f[i].Key {
+			continue
+		}
+		f[n] = f[i]
+		n++
+	}
+	for i := n; i < len(f); i++ {
+		f[i] = Field{}
+	}
+	return dst[:start+n]
+}
+
+// sortedFields returns the fields of the event using the event's internal buffer.
+func (e *Event) sortedFields() []Field {
+	e.sorted = e.AppendFields(e.sorted[:0])
+	return e.sorted
+}
@@ -0,0 +1,224 @@
+package xlog
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+var eventNow = time.Date(2017, 1, 2, 3, 4, 5, 6, time.UTC)
+
+type testEventOutput struct {
+	events []map[string]interface{}
+}
+
+func (o *testEventOutput) Write(fields map[string]interface{}) error {
+	panic("Write should not be called")
+}
+
+func (o *testEventOutput) WriteEvent(e *Event) error {
+	if o.events != nil {
+		o.events = append(o.events, e.Fields())
+	}
+	return nil
+}
+
+func TestEventDisabled(t *testing.T) {
+	l := New(Config{Level: xlog.LevelInfo, Output: Discard})
+	e := l.DebugEvent()
+	assert.Nil(t, e)
+	e.Str("foo", "bar").Int("n", 1).Err(errors.New("err")).Msg("test")
+	e.Msgf("test %d", 1)
+}
+
+func TestEventFields(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, Fields: xlog.F{"ctx": "val"}, NowGetter: func() time.Time { return eventNow }})
+	err := errors.New("some error")
+	l.InfoEvent().
+		Str("str", "foo").
+		Int("int", -1).
+		Int64("int64", 2).
+		Uint64("uint64", math.MaxUint64).
+		Float64("float64", 1.5).
+		Bool("bool", true).
+		Time("time2", eventNow).
+		Dur("dur", time.Second).
+		Err(err).
+		Interface("iface", []int{1}).
+		Str("ctx", "overridden").
+		Msgf("test %d", 1)
+	last := o.get()
+	assert.Contains(t, last["file"], "event_test.go:")
+	delete(last, "file")
+	assert.Equal(t, map[string]interface{}{
+		"time":    eventNow,
+		"level":   "info",
+		"message": "test 1",
+		"ctx":     "val",
+		"str":     "foo",
+		"int":     int64(-1),
+		"int64":   int64(2),
+		"uint64":  uint64(math.MaxUint64),
+		"float64": 1.5,
+		"bool":    true,
+		"time2":   eventNow,
+		"dur":     time.Second,
+		"error":   err,
+		"iface":   []int{1},
+	}, last)
+
+	l.WarnEvent().Err(nil).Msg("test")
+	last = o.get()
+	assert.Equal(t, "warn", last["level"])
+	_, found := last["error"]
+	assert.False(t, found)
+}
+
+func TestEventLevels(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o})
+	l.DebugEvent().Msg("test")
+	assert.Equal(t, "debug", o.get()["level"])
+	l.InfoEvent().Msg("test")
+	assert.Equal(t, "info", o.get()["level"])
+	l.WarnEvent().Msg("test")
+	assert.Equal(t, "warn", o.get()["level"])
+	l.ErrorEvent().Msg("test")
+	assert.Equal(t, "error", o.get()["level"])
+}
+
+func TestEventFatal(t *testing.T) {
+	e := exit1
+	exited := 0
+	exit1 = func() { exited++ }
+	defer func() { exit1 = e }()
+	o := newTestOutput()
+	l := New(Config{Output: NewOutputChannel(o)})
+	l.FatalEvent().Str("foo", "bar").Msg("test")
+	last := o.get()
+	assert.Equal(t, "fatal", last["level"])
+	assert.Equal(t, "bar", last["foo"])
+	assert.Equal(t, 1, exited)
+
+	l2 := New(Config{Output: o}).(*logger)
+	l2.output = nil
+	l2.FatalEvent().Msg("test")
+	assert.True(t, o.empty())
+	assert.Equal(t, 2, exited)
+}
+
+func TestEventOutputChannel(t *testing.T) {
+	o := &testEventOutput{events: []map[string]interface{}{}}
+	oc := NewOutputChannel(o)
+	l := New(Config{Output: oc, NowGetter: func() time.Time { return eventNow }})
+	l.InfoEvent().Str("foo", "bar").Msg("test")
+	oc.Close()
+	if assert.Len(t, o.events, 1) {
+		assert.Equal(t, "bar", o.events[0]["foo"])
+		assert.Equal(t, "test", o.events[0]["message"])
+	}
+
+	mo := newTestOutput()
+	oc = NewOutputChannel(mo)
+	defer oc.Close()
+	l = New(Config{Output: oc, NowGetter: func() time.Time { return eventNow }})
+	l.InfoEvent().Str("foo", "bar").Msg("test")
+	assert.Equal(t, "bar", mo.get()["foo"])
+}
+
+func TestEventAppendFields(t *testing.T) {
+	e := &Event{
+		level:  xlog.LevelInfo,
+		msg:    "test",
+		time:   eventNow,
+		ctx:    xlog.F{"b": "ctx", "z": 1},
+		fields: []Field{{Key: "b", Type: StringType, String: "event"}, {Key: "a", Type: Int64Type, Integer: 1}, {Key: "a", Type: Int64Type, Integer: 2}},
+	}
+	keys := []string{}
+	for _, f := range e.AppendFields(nil) {
+		keys = append(keys, f.Key)
+	}
+	assert.Equal(t, []string{"a", "b", "level", "message", "time", "z"}, keys)
+	f := e.Fields()
+	assert.Equal(t, int64(2), f["a"])
+	assert.Equal(t, "ctx", f["b"])
+}
+
+func newEncoderTestEvent() *Event {
+	return &Event{
+		level: xlog.LevelWarn,
+		msg:   "test \"message\"\n<html> &  ",
+		time:  eventNow,
+		ctx:   xlog.F{"ctx": "val", "slice": []string{"a"}},
+		fields: []Field{
+			{Key: "file", Type: CallerType, String: "/some/path/file.go", Integer: 42},
+			{Key: "str", Type: StringType, String: "foo bar\x01\xff"},
+			{Key: "int", Type: Int64Type, Integer: -42},
+			{Key: "uint", Type: Uint64Type, Integer: -1},
+			{Key: "float", Type: Float64Type, Integer: int64(math.Float64bits(1.5))},
+			{Key: "small", Type: Float64Type, Integer: int64(math.Float64bits(1e-9))},
+			{Key: "large", Type: Float64Type, Integer: int64(math.Float64bits(1e21))},
+			{Key: "bool", Type: BoolType, Integer: 1},
+			{Key: "t", Type: TimeType, Time: eventNow},
+			{Key: "dur", Type: DurationType, Integer: int64(1500 * time.Millisecond)},
+			{Key: "err", Type: ErrorType, Interface: errors.New("some error")},
+			{Key: "iface", Type: InterfaceType, Interface: map[string]int{"a": 1}},
+		},
+	}
+}
+
+// assertWriteEvent asserts the JSON and logfmt outputs write the same message
+// for e with WriteEvent as with Write and returns the messages they wrote.
+func assertWriteEvent(t *testing.T, e *Event) []string {
+	var written []string
+	for _, newOutput := range []func(w *bytes.Buffer) xlog.Output{
+		func(w *bytes.Buffer) xlog.Output { return NewJSONOutput(w) },
+		func(w *bytes.Buffer) xlog.Output { return NewLogfmtOutput(w) },
+	} {
+		buf1 := &bytes.Buffer{}
+		buf2 := &bytes.Buffer{}
+		assert.NoError(t, newOutput(buf1).Write(e.Fields()))
+		assert.NoError(t, newOutput(buf2).(EventOutput).WriteEvent(e))
+		assert.Equal(t, buf1.String(), buf2.String())
+		written = append(written, buf2.String())
+	}
+	return written
+}
+
+func TestOutputWriteEvent(t *testing.T) {
+	e := newEncoderTestEvent()
+	assertWriteEvent(t, e)
+
+	e.fields = []Field{{Key: "nan", Type: Float64Type, Integer: int64(math.Float64bits(math.NaN()))}}
+	assert.Error(t, NewJSONOutput(&bytes.Buffer{}).(EventOutput).WriteEvent(e))
+}
+
+func TestLevelOutputWriteEvent(t *testing.T) {
+	oInfo := newTestOutput()
+	oWarn := &testEventOutput{events: []map[string]interface{}{}}
+	l := LevelOutput{Info: oInfo, Warn: oWarn}
+	e := newEncoderTestEvent()
+	assert.NoError(t, l.WriteEvent(e))
+	assert.Len(t, oWarn.events, 1)
+	assert.True(t, oInfo.empty())
+	e.level = xlog.LevelInfo
+	assert.NoError(t, l.WriteEvent(e))
+	assert.Equal(t, "info", oInfo.get()["level"])
+	e.level = xlog.LevelError
+	assert.NoError(t, l.WriteEvent(e))
+}
+
+func TestEventAllocs(t *testing.T) {
+	l := New(Config{Output: &testEventOutput{}})
+	err := errors.New("some error")
+	allocs := testing.AllocsPerRun(100, func() {
+		l.InfoEvent().Str("foo", "bar").Int("n", 3).Err(err).Msg("test")
+	})
+	assert.Equal(t, 0.0, allocs)
+}
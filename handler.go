@@ -1,4 +1,3 @@
-// +build go1.7
 
 package xlog
 
@@ -8,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/rs/xid"
+	"github.com/rs/xlog"
 )
 
 type key int
@@ -15,6 +15,8 @@ type key int
 const (
 	logKey key = iota
 	idKey
+	fieldsKey
+	traceKey
 )
 
 // IDFromContext returns the unique id associated to the request if any.
@@ -33,6 +35,10 @@ func IDFromRequest(r *http.Request) (xid.ID, bool) {
 
 // FromContext gets the logger out of the context.
 // If not logger is stored in the context, a NopLogger is returned.
+//
+// The fields stored in the context with WithFields are sent with the messages
+// of the returned logger. It is then a child of the stored logger: fields set
+// on it are not set on the stored logger.
 func FromContext(ctx context.Context) Logger {
 	if ctx == nil {
 		return NopLogger
@@ -41,9 +47,30 @@ func FromContext(ctx context.Context) Logger {
 	if !ok {
 		return NopLogger
 	}
+	if fields := contextFields(ctx); len(fields) > 0 {
+		if l, ok := l.(*logger); ok {
+			return l.withContext(fields)
+		}
+		return l.With(fields)
+	}
 	return l
 }
 
+// WithFields returns a copy of the parent context storing fields in addition
+// to the ones already stored in it. They are sent with the messages of the
+// loggers obtained from the context with FromContext, so code without access
+// to a logger can add fields to the messages sent down the call chain.
+func WithFields(ctx context.Context, fields xlog.F) context.Context {
+	return context.WithValue(ctx, fieldsKey, mergeFields(contextFields(ctx), nil, fields))
+}
+
+// contextFields returns the fields stored in ctx by WithFields. The returned
+// map must not be modified.
+func contextFields(ctx context.Context) xlog.F {
+	f, _ := ctx.Value(fieldsKey).(xlog.F)
+	return f
+}
+
 // FromRequest gets the logger in the request's context.
 // This is a shortcut for xlog.FromContext(r.Context())
 func FromRequest(r *http.Request) Logger {
@@ -1,4 +1,3 @@
-// +build go1.7
 
 package xlog
 
@@ -21,6 +20,90 @@ func TestFromContext(t *testing.T) {
 	assert.Equal(t, l, FromContext(ctx))
 }
 
+func TestWithFields(t *testing.T) {
+	ctx := WithFields(context.Background(), xlog.F{"a": 1, "b": 1})
+	ctx2 := WithFields(ctx, xlog.F{"b": 2})
+	assert.Equal(t, xlog.F{"a": 1, "b": 1}, contextFields(ctx))
+	assert.Equal(t, xlog.F{"a": 1, "b": 2}, contextFields(ctx2))
+	assert.Nil(t, contextFields(context.Background()))
+
+	// Fields are lost without a logger
+	assert.Equal(t, NopLogger, FromContext(ctx2))
+
+	o := newTestOutput()
+	l := New(Config{Output: o, DisableCaller: true, Fields: xlog.F{"b": 0, "c": 0}}).(*logger)
+	ctx = NewContext(ctx2, l)
+	ctx = WithFields(ctx, xlog.F{"d": 3})
+	cl := FromContext(ctx)
+	cl.Info("test")
+	last := o.get()
+	delete(last, KeyTime)
+	assert.Equal(t, map[string]interface{}{"level": "info", "message": "test", "a": 1, "b": 2, "c": 0, "d": 3}, last)
+	cl.InfoEvent().Msg("test")
+	last = o.get()
+	delete(last, KeyTime)
+	assert.Equal(t, map[string]interface{}{"level": "info", "message": "test", "a": 1, "b": 2, "c": 0, "d": 3}, last)
+
+	// Fields set thru the context logger are not set on the stored logger
+	cl.SetField("e", 5)
+	assert.Equal(t, xlog.F{"b": 0, "c": 0}, l.GetFields())
+	assert.Equal(t, xlog.F{"b": 0, "c": 0, "e": 5}, cl.GetFields())
+	l.SetField("f", 6)
+	assert.NotContains(t, cl.GetFields(), "f")
+
+	// The stored logger does not send the context fields
+	l.Info("test")
+	assert.NotContains(t, o.get(), "a")
+
+	// Children keep the context fields
+	cl.With(xlog.F{"g": 7}).Info("test")
+	last = o.get()
+	assert.Equal(t, 1, last["a"])
+	assert.Equal(t, 7, last["g"])
+
+	// As well as the fields of the context logger
+	sl := cl.AddCallerSkip(1)
+	sl.SetField("h", 8)
+	assert.NotContains(t, cl.GetFields(), "h")
+	sl.Info("test")
+	last = o.get()
+	assert.Equal(t, 1, last["a"])
+	assert.Equal(t, 5, last["e"])
+	assert.Equal(t, 8, last["h"])
+}
+
+func TestWithFieldsPooledLogger(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, DisableCaller: true, Fields: xlog.F{"id": 1}}).(*logger)
+	ctx := WithFields(NewContext(context.Background(), l), xlog.F{"a": 1})
+	cl := FromContext(ctx)
+	// The request logger is returned to the pool and reused by another request
+	l.close()
+	l2 := New(Config{Output: o, DisableCaller: true, Fields: xlog.F{"id": 2}})
+	l2.SetField("b", 2)
+	assert.Equal(t, xlog.F{"id": 1}, cl.GetFields())
+	cl.Info("test")
+	last := o.get()
+	assert.Equal(t, 1, last["id"])
+	assert.Equal(t, 1, last["a"])
+	assert.NotContains(t, last, "b")
+}
+
+// wrappedLogger is a Logger implementation other than *logger.
+type wrappedLogger struct {
+	Logger
+}
+
+func TestWithFieldsOtherLogger(t *testing.T) {
+	o := &RecorderOutput{}
+	l := wrappedLogger{New(Config{Output: o})}
+	ctx := WithFields(NewContext(context.Background(), l), xlog.F{"a": 1})
+	FromContext(ctx).Info("test")
+	if assert.Len(t, o.Messages, 1) {
+		assert.Equal(t, 1, o.Messages[0]["a"])
+	}
+}
+
 func TestNewHandler(t *testing.T) {
 	c := Config{
 		Level:  xlog.LevelInfo,
@@ -0,0 +1,34 @@
+package xlog
+
+import (
+	"github.com/rs/xlog"
+)
+
+// Hook is run synchronously by the logger for every message passing the level
+// check, before it is sent to the output. Unlike output wrappers, hooks run on
+// the caller's go routine.
+type Hook interface {
+	// Run is called with the level, the message and the fields of the message,
+	// including the time, level, message and file fields. The fields may be
+	// modified. If Run returns false, the message is dropped and the following
+	// hooks are not run.
+	Run(level xlog.Level, msg string, fields map[string]interface{}) bool
+}
+
+// HookFunc is an adapter to allow the use of ordinary functions as Hook.
+type HookFunc func(level xlog.Level, msg string, fields map[string]interface{}) bool
+
+// Run calls h(level, msg, fields).
+func (h HookFunc) Run(level xlog.Level, msg string, fields map[string]interface{}) bool {
+	return h(level, msg, fields)
+}
+
+// runHooks runs the logger's hooks and returns false if the message must be dropped.
+func (l *logger) runHooks(level xlog.Level, msg string, fields map[string]interface{}) bool {
+	for _, h := range l.hooks {
+		if !h.Run(level, msg, fields) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package xlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks(t *testing.T) {
+	o := newTestOutput()
+	calls := []string{}
+	l := New(Config{
+		Output:    o,
+		NowGetter: func() time.Time { return fakeNow },
+		Hooks: []Hook{
+			HookFunc(func(level xlog.Level, msg string, fields map[string]interface{}) bool {
+				calls = append(calls, "first")
+				fields["tenant"] = "acme"
+				delete(fields, "secret")
+				return msg != "drop"
+			}),
+			HookFunc(func(level xlog.Level, msg string, fields map[string]interface{}) bool {
+				calls = append(calls, "second")
+				assert.Equal(t, xlog.LevelInfo, level)
+				assert.Equal(t, msg, fields["message"])
+				return true
+			}),
+		},
+	})
+	l.Info("test", xlog.F{"secret": "foo"})
+	last := o.get()
+	delete(last, "file")
+	assert.Equal(t, map[string]interface{}{"time": fakeNow, "level": "info", "message": "test", "tenant": "acme"}, last)
+	assert.Equal(t, []string{"first", "second"}, calls)
+
+	calls = calls[:0]
+	l.Info("drop")
+	assert.True(t, o.empty())
+	assert.Equal(t, []string{"first"}, calls)
+
+	// Hooks are inherited and also run for events
+	calls = calls[:0]
+	l.With(xlog.F{"foo": "bar"}).InfoEvent().Str("secret", "foo").Msg("test")
+	last = o.get()
+	assert.Equal(t, "acme", last["tenant"])
+	assert.Equal(t, "bar", last["foo"])
+	_, found := last["secret"]
+	assert.False(t, found)
+	assert.Equal(t, []string{"first", "second"}, calls)
+
+	calls = calls[:0]
+	l.Named("sub").InfoEvent().Msg("drop")
+	assert.True(t, o.empty())
+	assert.Equal(t, []string{"first"}, calls)
+
+	// Hooks are not run for disabled levels
+	calls = calls[:0]
+	l = New(Config{Level: xlog.LevelError, Output: o, Hooks: []Hook{HookFunc(func(level xlog.Level, msg string, fields map[string]interface{}) bool {
+		calls = append(calls, "called")
+		return true
+	})}})
+	l.Info("test")
+	assert.Len(t, calls, 0)
+}
@@ -0,0 +1,113 @@
+package xlog
+
+import (
+	"sort"
+	"sync"
+)
+
+// FieldKeys defines the names of the fields set by a logger and read by the
+// built-in outputs. Empty names are replaced by the default ones defined by the
+// KeyTime, KeyMessage, … variables.
+type FieldKeys struct {
+	Time    string
+	Message string
+	Level   string
+	File    string
+	Func    string
+	Logger  string
+	Error   string
+	Stack   string
+	Fields  string
+	// RequestID, TraceID and SpanID are the names of the fields set by the Ctx
+	// methods of a logger with the ids found in the context.
+	RequestID string
+	TraceID   string
+	SpanID    string
+}
+
+// DefaultFieldKeys returns the field names defined by the KeyTime, KeyMessage, …
+// variables.
+func DefaultFieldKeys() FieldKeys {
+	return FieldKeys{
+		Time:    KeyTime,
+		Message: KeyMessage,
+		Level:   KeyLevel,
+		File:    KeyFile,
+		Func:    KeyFunc,
+		Logger:  KeyLogger,
+		Error:   KeyError,
+		Stack:   KeyStack,
+		Fields:  KeyFields,
+
+		RequestID: KeyRequestID,
+		TraceID:   KeyTraceID,
+		SpanID:    KeySpanID,
+	}
+}
+
+// withDefaults returns k with its empty names replaced by the default ones.
+func (k FieldKeys) withDefaults() FieldKeys {
+	d := DefaultFieldKeys()
+	for _, p := range []struct{ k, d *string }{
+		{&k.Time, &d.Time},
+		{&k.Message, &d.Message},
+		{&k.Level, &d.Level},
+		{&k.File, &d.File},
+		{&k.Func, &d.Func},
+		{&k.Logger, &d.Logger},
+		{&k.Error, &d.Error},
+		{&k.Stack, &d.Stack},
+		{&k.Fields, &d.Fields},
+		{&k.RequestID, &d.RequestID},
+		{&k.TraceID, &d.TraceID},
+		{&k.SpanID, &d.SpanID},
+	} {
+		if *p.k == "" {
+			*p.k = *p.d
+		}
+	}
+	return k
+}
+
+var (
+	defaultKeysOnce sync.Once
+	defaultKeysVal  FieldKeys
+)
+
+// defaultKeys returns the default field keys read once, on first use, for
+// the values created without a logger or constructor resolving them, like a
+// LevelOutput without Keys.
+func defaultKeys() *FieldKeys {
+	defaultKeysOnce.Do(func() {
+		defaultKeysVal = DefaultFieldKeys()
+	})
+	return &defaultKeysVal
+}
+
+// isReserved returns true if key is the name of a field set by the logger.
+func (k *FieldKeys) isReserved(key string) bool {
+	switch key {
+	case k.Time, k.Level, k.Message, k.File, k.Func, k.Stack:
+		return true
+	}
+	return false
+}
+
+// sortedKeys returns the keys of fields but the time, level and message ones,
+// sorted by name. The stack key is omitted if skipStack is true.
+func (k *FieldKeys) sortedKeys(fields map[string]interface{}, skipStack bool) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		switch key {
+		case k.Level, k.Message, k.Time:
+			continue
+		case k.Stack:
+			if skipStack {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+package xlog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldKeysWithDefaults(t *testing.T) {
+	assert.Equal(t, DefaultFieldKeys(), FieldKeys{}.withDefaults())
+	k := FieldKeys{Time: "@timestamp", Message: "msg"}.withDefaults()
+	assert.Equal(t, "@timestamp", k.Time)
+	assert.Equal(t, "msg", k.Message)
+	assert.Equal(t, KeyLevel, k.Level)
+	assert.Equal(t, KeyFields, k.Fields)
+}
+
+func TestFieldKeysIsReserved(t *testing.T) {
+	k := FieldKeys{Level: "severity"}.withDefaults()
+	for _, key := range []string{KeyTime, "severity", KeyMessage, KeyFile, KeyFunc, KeyStack} {
+		assert.True(t, k.isReserved(key), key)
+	}
+	assert.False(t, k.isReserved(KeyLevel))
+	assert.False(t, k.isReserved(KeyLogger))
+	assert.False(t, k.isReserved("id"))
+}
+
+func TestFieldKeysSortedKeys(t *testing.T) {
+	k := FieldKeys{Message: "msg"}.withDefaults()
+	fields := map[string]interface{}{"msg": "", "level": "", "time": "", "stack": "", "b": 1, "a": 2}
+	assert.Equal(t, []string{"a", "b", "stack"}, k.sortedKeys(fields, false))
+	assert.Equal(t, []string{"a", "b"}, k.sortedKeys(fields, true))
+}
+
+var testFieldKeys = FieldKeys{Time: "@timestamp", Message: "msg", Level: "severity", Error: "err", Logger: "name"}
+
+func TestLoggerFieldKeys(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, FieldKeys: testFieldKeys, CallerFunc: true}).Named("app")
+	l.Info("test", xlog.F{"msg": "field"})
+	last := o.get()
+	assert.Equal(t, "info", last["severity"])
+	assert.Equal(t, "test", last["msg"])
+	assert.Equal(t, "app", last["name"])
+	assert.Contains(t, last, "@timestamp")
+	assert.Contains(t, last, KeyFile)
+	assert.Contains(t, last, KeyFunc)
+	assert.NotContains(t, last, KeyLevel)
+	assert.NotContains(t, last, KeyMessage)
+	assert.Equal(t, Namespace{"msg": "field"}, last[KeyFields])
+
+	err := errors.New("some error")
+	l.InfoEvent().Err(err).Msg("event")
+	last = o.get()
+	assert.Equal(t, "info", last["severity"])
+	assert.Equal(t, "event", last["msg"])
+	assert.Equal(t, err, last["err"])
+	assert.NotContains(t, last, KeyError)
+}
+
+func TestFieldKeysOutputs(t *testing.T) {
+	now := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	fields := map[string]interface{}{"@timestamp": now, "severity": "warn", "msg": "test", "foo": "bar"}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, NewLogfmtOutputKeys(buf, testFieldKeys).Write(fields))
+	assert.Equal(t, "severity=warn msg=test @timestamp=\"2000-01-02 03:04:05 +0000 UTC\" foo=bar\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, consoleOutput{w: buf, keys: testFieldKeys.withDefaults()}.Write(fields))
+	assert.Equal(t, "2000/01/02 03:04:05 \x1b[33mWARN\x1b[0m test \x1b[32mfoo\x1b[0m=bar\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, NewLogstashOutputKeys(buf, testFieldKeys).Write(fields))
+	assert.Equal(t, `{"@timestamp":"2000-01-02T03:04:05Z","@version":1,"foo":"bar","msg":"test","severity":"WARN"}`, buf.String())
+
+	warn := newTestOutput()
+	assert.NoError(t, LevelOutput{Keys: testFieldKeys, Warn: warn}.Write(fields))
+	assert.Equal(t, fields, warn.get())
+}
+
+func TestFieldKeysEventOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(Config{
+		Output:        NewLogfmtOutputKeys(buf, testFieldKeys),
+		FieldKeys:     testFieldKeys,
+		DisableCaller: true,
+		NowGetter:     func() time.Time { return time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC) },
+	})
+	l.WarnEvent().Str("foo", "bar").Msg("test")
+	assert.Equal(t, "severity=warn msg=test @timestamp=\"2000-01-02 03:04:05 +0000 UTC\" foo=bar\n", buf.String())
+}
+
+func TestFieldKeysResolvedOnce(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o := NewLogfmtOutput(buf)
+	oldKeyLevel := KeyLevel
+	KeyLevel = "severity"
+	defer func() { KeyLevel = oldKeyLevel }()
+	// Outputs keep the keys read by their constructor
+	assert.NoError(t, o.Write(map[string]interface{}{"level": "warn", "message": "test"}))
+	assert.Equal(t, "level=warn message=test time=null\n", buf.String())
+}
@@ -0,0 +1,132 @@
+package xlog
+
+import (
+	"fmt"
+
+	"github.com/rs/xlog"
+)
+
+// LazyValue is a field value computed only when a message passes the level
+// check. It is called once per message on the logging go routine, before the
+// hooks are run. Filtered messages never call it.
+type LazyValue func() interface{}
+
+// DeferredValue is a LazyValue called by the OutputChannel go routine when the
+// logger's output is an OutputChannel, so its cost is not paid by the logging go
+// routine. As it runs after the log call returned, the function must not depend
+// on state modified by the caller. With other outputs, it is called on the
+// logging go routine like a LazyValue.
+type DeferredValue func() interface{}
+
+// resolveValue returns the value computed by v if v is a LazyValue, or a
+// DeferredValue when deferred is true, or the value returned by LogValue if v is
+// a LogValuer. Values returned by those are resolved the same way. The returned
+// bool is false if v was not resolved.
+func resolveValue(v interface{}, deferred bool) (interface{}, bool) {
+	resolved := false
+	for i := 0; ; i++ {
+		if i == maxLogValueDepth {
+			return fmt.Errorf("LogValue called too many times on %T", v), true
+		}
+		switch f := v.(type) {
+		case LazyValue:
+			v = lazyValue(f, f)
+		case DeferredValue:
+			if !deferred {
+				return v, resolved
+			}
+			v = lazyValue(f, f)
+		case LogValuer:
+			v = logValue(f)
+		case Namespace:
+			if ns, ok := resolveNamespace(f, deferred); ok {
+				return ns, true
+			}
+			return v, resolved
+		default:
+			return v, resolved
+		}
+		resolved = true
+	}
+}
+
+// lazyValue calls f, the function of the lazy value v. A panic in f, like when
+// v is nil, is returned as an error value.
+func lazyValue(f func() interface{}, v interface{}) (r interface{}) {
+	defer func() {
+		if err := recover(); err != nil {
+			r = fmt.Errorf("%T panicked: %v", v, err)
+		}
+	}()
+	return f()
+}
+
+// resolveNamespace returns a copy of ns with its values resolved by
+// resolveValue. The returned bool is false and ns is returned if no value needed
+// to be resolved.
+func resolveNamespace(ns Namespace, deferred bool) (Namespace, bool) {
+	var c Namespace
+	for k, v := range ns {
+		r, ok := resolveValue(v, deferred)
+		if !ok {
+			continue
+		}
+		if c == nil {
+			c = make(Namespace, len(ns))
+			for k, v := range ns {
+				c[k] = v
+			}
+		}
+		c[k] = r
+	}
+	if c == nil {
+		return ns, false
+	}
+	return c, true
+}
+
+// resolveFields replaces the lazy values and LogValuer of fields by their
+// computed value.
+func resolveFields(fields map[string]interface{}, deferred bool) {
+	for k, v := range fields {
+		if r, ok := resolveValue(v, deferred); ok {
+			fields[k] = r
+		}
+	}
+}
+
+// resolveLazy replaces the lazy values and LogValuer of the event and logger
+// fields by their computed value. The logger fields are copied if they contain lazy values as
+// they are shared with other messages.
+func (e *Event) resolveLazy(deferred bool) {
+	for i := range e.fields {
+		if v, ok := resolveValue(e.fields[i].Interface, deferred); ok {
+			e.fields[i].Type = InterfaceType
+			e.fields[i].Interface = v
+		}
+	}
+	var ctx xlog.F
+	for k, v := range e.ctx {
+		r, ok := resolveValue(v, deferred)
+		if !ok {
+			continue
+		}
+		if ctx == nil {
+			ctx = make(xlog.F, len(e.ctx))
+			for k, v := range e.ctx {
+				ctx[k] = v
+			}
+		}
+		ctx[k] = r
+	}
+	if ctx != nil {
+		e.ctx = ctx
+	}
+}
+
+// deferLazy returns true if the DeferredValue fields must be left to the output
+// to resolve.
+func (l *logger) deferLazy() bool {
+	_, ok := l.output.(*OutputChannel)
+	return ok
+}
@@ -0,0 +1,128 @@
+package xlog
+
+import (
+	"testing"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLazyValueFiltered(t *testing.T) {
+	calls := 0
+	lazy := LazyValue(func() interface{} { calls++; return "val" })
+	deferred := DeferredValue(func() interface{} { calls++; return "val" })
+	o := newTestOutput()
+	l := New(Config{Level: xlog.LevelInfo, Output: o, Fields: xlog.F{"ctx": lazy}})
+	l.Debug("test", xlog.F{"lazy": lazy, "deferred": deferred})
+	l.DebugEvent().Interface("lazy", lazy).Msg("test")
+	assert.True(t, o.empty())
+	assert.Equal(t, 0, calls)
+}
+
+func TestLazyValue(t *testing.T) {
+	calls := 0
+	lazy := LazyValue(func() interface{} { calls++; return calls })
+	var hooked map[string]interface{}
+	o := newTestOutput()
+	l := New(Config{Output: o, Fields: xlog.F{"ctx": lazy}, Hooks: []Hook{
+		HookFunc(func(level xlog.Level, msg string, fields map[string]interface{}) bool {
+			hooked = fields
+			return true
+		}),
+	}})
+	l.Info("test", xlog.F{"lazy": lazy})
+	last := o.get()
+	assert.Equal(t, 2, calls)
+	assert.IsType(t, 0, hooked["ctx"])
+	assert.IsType(t, 0, hooked["lazy"])
+	assert.IsType(t, 0, last["ctx"])
+	assert.IsType(t, 0, last["lazy"])
+	l.Info("test")
+	assert.Equal(t, 3, o.get()["ctx"])
+	assert.IsType(t, LazyValue(nil), l.GetFields()["ctx"])
+}
+
+func TestLazyValueEvent(t *testing.T) {
+	calls := 0
+	lazy := LazyValue(func() interface{} { calls++; return calls })
+	o := newTestOutput()
+	l := New(Config{Output: o, Fields: xlog.F{"ctx": lazy}})
+	l.InfoEvent().Interface("lazy", lazy).Msg("test")
+	last := o.get()
+	assert.Equal(t, 2, calls)
+	assert.IsType(t, 0, last["ctx"])
+	assert.IsType(t, 0, last["lazy"])
+	assert.IsType(t, LazyValue(nil), l.GetFields()["ctx"])
+}
+
+func TestDeferredValue(t *testing.T) {
+	called := make(chan struct{}, 2)
+	deferred := DeferredValue(func() interface{} { called <- struct{}{}; return "val" })
+	var hooked interface{}
+	hooks := []Hook{
+		HookFunc(func(level xlog.Level, msg string, fields map[string]interface{}) bool {
+			hooked = fields["deferred"]
+			return true
+		}),
+	}
+
+	// Resolved by the output channel after the hooks
+	o := newTestOutput()
+	oc := NewOutputChannel(o)
+	defer oc.Close()
+	l := New(Config{Output: oc, Hooks: hooks})
+	l.Info("test", xlog.F{"deferred": deferred})
+	assert.IsType(t, DeferredValue(nil), hooked)
+	assert.Equal(t, "val", o.get()["deferred"])
+	<-called
+
+	// Resolved on the calling go routine with other outputs
+	l = New(Config{Output: o, Hooks: hooks})
+	l.Info("test", xlog.F{"deferred": deferred})
+	assert.Len(t, called, 1)
+	assert.Equal(t, "val", hooked)
+	assert.Equal(t, "val", o.get()["deferred"])
+	<-called
+}
+
+func TestDeferredValueEvent(t *testing.T) {
+	deferred := DeferredValue(func() interface{} { return "val" })
+	o := &testEventOutput{events: []map[string]interface{}{}}
+	oc := NewOutputChannel(o)
+	l := New(Config{Output: oc, Fields: xlog.F{"ctx": deferred}})
+	l.InfoEvent().Interface("deferred", deferred).Msg("test")
+	oc.Close()
+	if assert.Len(t, o.events, 1) {
+		assert.Equal(t, "val", o.events[0]["ctx"])
+		assert.Equal(t, "val", o.events[0]["deferred"])
+	}
+
+	mo := newTestOutput()
+	l = New(Config{Output: mo})
+	l.InfoEvent().Interface("deferred", deferred).Msg("test")
+	assert.Equal(t, "val", mo.get()["deferred"])
+}
+
+func TestLazyValuePanic(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o})
+	l.Info("test", xlog.F{"lazy": LazyValue(nil)})
+	if err, ok := o.get()["lazy"].(error); assert.True(t, ok) {
+		assert.Contains(t, err.Error(), "xlog.LazyValue panicked")
+	}
+}
+
+func TestDeferredValuePanic(t *testing.T) {
+	deferred := DeferredValue(func() interface{} { panic("boom") })
+	o := newTestOutput()
+	oc := NewOutputChannel(o)
+	defer oc.Close()
+	l := New(Config{Output: oc})
+	l.Info("test", xlog.F{"deferred": deferred})
+	if err, ok := o.get()["deferred"].(error); assert.True(t, ok) {
+		assert.Equal(t, "xlog.DeferredValue panicked: boom", err.Error())
+	}
+	// The consumer survived the panic
+	l.Info("test", xlog.F{"id": 1})
+	assert.Equal(t, 1, o.get()["id"])
+}
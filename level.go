@@ -0,0 +1,220 @@
+package xlog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/xlog"
+)
+
+// Levels added to the ones defined by github.com/rs/xlog.
+const (
+	// LevelTrace is the level of messages more verbose than debug ones.
+	LevelTrace = xlog.LevelDebug - 1
+	// LevelPanic is the level of messages logged before calling panic.
+	LevelPanic = xlog.LevelFatal + 1
+)
+
+// LevelDef defines a custom level registered with RegisterLevel.
+type LevelDef struct {
+	// Name is the name of the level as written in the KeyLevel field.
+	Name string
+	// Severity is the standard level the custom level is equivalent to when
+	// messages are filtered by level or routed by LevelOutput.
+	Severity xlog.Level
+	// Color is the color of the level in the console output.
+	Color Color
+}
+
+// firstCustomLevel is the value of the first level returned by RegisterLevel.
+const firstCustomLevel xlog.Level = 100
+
+// levelRegistry holds the registered levels. A registry is never modified once
+// published so it can be read without lock.
+type levelRegistry struct {
+	defs  map[xlog.Level]LevelDef
+	names map[string]xlog.Level
+}
+
+var (
+	levelsMu    sync.Mutex
+	levels      atomic.Value // *levelRegistry
+	emptyLevels = &levelRegistry{}
+)
+
+// loadLevels returns the current registry. Levels may be registered while
+// package variables are initialized so the registry is not set in an init
+// function.
+func loadLevels() *levelRegistry {
+	if r, ok := levels.Load().(*levelRegistry); ok {
+		return r
+	}
+	return emptyLevels
+}
+
+// RegisterLevel registers a custom level and returns its value to use with
+// Logger.OutputF, AtomicLevel, … Custom levels are usually registered in an
+// init function. RegisterLevel panics if a level with the same name exists.
+func RegisterLevel(def LevelDef) xlog.Level {
+	if def.Severity < LevelTrace || def.Severity > LevelPanic {
+		panic(fmt.Sprintf("xlog: invalid severity %d for level %q", def.Severity, def.Name))
+	}
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	if _, err := ParseLevel(def.Name); err == nil {
+		panic(fmt.Sprintf("xlog: level %q already registered", def.Name))
+	}
+	old := loadLevels()
+	r := &levelRegistry{
+		defs:  make(map[xlog.Level]LevelDef, len(old.defs)+1),
+		names: make(map[string]xlog.Level, len(old.names)+1),
+	}
+	for k, v := range old.defs {
+		r.defs[k] = v
+	}
+	for k, v := range old.names {
+		r.names[k] = v
+	}
+	level := firstCustomLevel + xlog.Level(len(old.defs))
+	r.defs[level] = def
+	r.names[def.Name] = level
+	levels.Store(r)
+	return level
+}
+
+// ParseLevel returns the level with the given name, either a standard or a
+// registered level.
+func ParseLevel(name string) (xlog.Level, error) {
+	switch name {
+	case "trace":
+		return LevelTrace, nil
+	case "panic":
+		return LevelPanic, nil
+	}
+	if level, err := xlog.LevelFromString(name); err == nil {
+		return level, nil
+	}
+	if level, ok := loadLevels().names[name]; ok {
+		return level, nil
+	}
+	return 0, fmt.Errorf("unknown level: %s", name)
+}
+
+// levelDef returns the definition of a registered level.
+func levelDef(level xlog.Level) (LevelDef, bool) {
+	def, ok := loadLevels().defs[level]
+	return def, ok
+}
+
+// levelName returns the name of the level as written in the KeyLevel field.
+func levelName(level xlog.Level) string {
+	switch level {
+	case LevelTrace:
+		return "trace"
+	case LevelPanic:
+		return "panic"
+	}
+	if level >= firstCustomLevel {
+		if def, ok := levelDef(level); ok {
+			return def.Name
+		}
+	}
+	return level.String()
+}
+
+// severity returns the standard level equivalent to level.
+func severity(level xlog.Level) xlog.Level {
+	if level >= firstCustomLevel {
+		if def, ok := levelDef(level); ok {
+			return def.Severity
+		}
+	}
+	return level
+}
+
+// levelColor returns the console color of the level with the given name.
+func levelColor(name string) Color {
+	switch name {
+	case "trace", "debug":
+		return ColorGray
+	case "warn":
+		return ColorYellow
+	case "error", "panic":
+		return ColorRed
+	}
+	r := loadLevels()
+	if def, ok := r.defs[r.names[name]]; ok && def.Color != 0 {
+		return def.Color
+	}
+	return ColorBlue
+}
+
+// AtomicLevel is a log level which can be safely changed at runtime while
+// loggers are reading it. The zero value is set to the debug level.
+type AtomicLevel struct {
+	l int32
+}
+
+// NewAtomicLevel returns an AtomicLevel initialized to the given level.
+func NewAtomicLevel(level xlog.Level) *AtomicLevel {
+	a := &AtomicLevel{}
+	a.SetLevel(level)
+	return a
+}
+
+// Level returns the current level.
+func (a *AtomicLevel) Level() xlog.Level {
+	return xlog.Level(atomic.LoadInt32(&a.l))
+}
+
+// SetLevel changes the level. All loggers referencing this AtomicLevel see
+// the new level for their next message.
+func (a *AtomicLevel) SetLevel(level xlog.Level) {
+	atomic.StoreInt32(&a.l, int32(level))
+}
+
+// String returns the string representation of the current level.
+func (a *AtomicLevel) String() string {
+	return levelName(a.Level())
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (a *AtomicLevel) MarshalText() ([]byte, error) {
+	return []byte(levelName(a.Level())), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (a *AtomicLevel) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	a.SetLevel(level)
+	return nil
+}
+
+// LevelRules maps logger names to the level to use for loggers created with
+// Logger.Named. A rule applies to the logger with the exact same name and to all
+// its descendants (i.e.: the rule "payment" applies to "payment.gateway" but not
+// to "payments").
+type LevelRules map[string]*AtomicLevel
+
+// match returns the level of the rule with the longest name matching the given
+// logger name or nil if no rule matches.
+func (r LevelRules) match(name string) *AtomicLevel {
+	if len(r) == 0 {
+		return nil
+	}
+	for {
+		if a, ok := r[name]; ok {
+			return a
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i == -1 {
+			return nil
+		}
+		name = name[:i]
+	}
+}
@@ -0,0 +1,160 @@
+package xlog
+
+import (
+	"testing"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicLevel(t *testing.T) {
+	a := &AtomicLevel{}
+	assert.Equal(t, xlog.LevelDebug, a.Level())
+	a.SetLevel(xlog.LevelWarn)
+	assert.Equal(t, xlog.LevelWarn, a.Level())
+	assert.Equal(t, "warn", a.String())
+	assert.Equal(t, xlog.LevelError, NewAtomicLevel(xlog.LevelError).Level())
+}
+
+func TestAtomicLevelText(t *testing.T) {
+	a := NewAtomicLevel(xlog.LevelInfo)
+	b, err := a.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "info", string(b))
+	assert.NoError(t, a.UnmarshalText([]byte("error")))
+	assert.Equal(t, xlog.LevelError, a.Level())
+	assert.Error(t, a.UnmarshalText([]byte("foo")))
+	assert.Equal(t, xlog.LevelError, a.Level())
+}
+
+func TestAtomicLevelLogger(t *testing.T) {
+	o := newTestOutput()
+	a := NewAtomicLevel(xlog.LevelInfo)
+	l := New(Config{Level: xlog.LevelFatal, AtomicLevel: a, Output: o})
+	c := l.With(xlog.F{"foo": "bar"})
+	l.Debug("test")
+	c.Debug("test")
+	assert.True(t, o.empty())
+	a.SetLevel(xlog.LevelDebug)
+	l.Debug("test")
+	assert.Equal(t, "debug", o.get()["level"])
+	c.Debug("test")
+	assert.Equal(t, "bar", o.get()["foo"])
+}
+
+func TestLevelRulesMatch(t *testing.T) {
+	p := NewAtomicLevel(xlog.LevelInfo)
+	pg := NewAtomicLevel(xlog.LevelDebug)
+	r := LevelRules{"payment": p, "payment.gateway": pg}
+	assert.Equal(t, p, r.match("payment"))
+	assert.Equal(t, p, r.match("payment.db"))
+	assert.Equal(t, pg, r.match("payment.gateway"))
+	assert.Equal(t, pg, r.match("payment.gateway.http"))
+	assert.Nil(t, r.match("payments"))
+	assert.Nil(t, r.match("user"))
+	assert.Nil(t, LevelRules(nil).match("payment"))
+}
+
+func TestNamedLevels(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{
+		Level:  xlog.LevelWarn,
+		Output: o,
+		Levels: LevelRules{"payment.db": NewAtomicLevel(xlog.LevelDebug)},
+	})
+	p := l.Named("payment")
+	g := p.Named("gateway")
+	d := p.Named("db")
+	p.Info("test")
+	g.Info("test")
+	assert.True(t, o.empty())
+	d.Debug("test")
+	last := o.get()
+	assert.Equal(t, "payment.db", last["logger"])
+	d.Named("conn").Debug("test")
+	last = o.get()
+	assert.Equal(t, "payment.db.conn", last["logger"])
+	g.Warn("test")
+	last = o.get()
+	assert.Equal(t, "payment.gateway", last["logger"])
+}
+
+var levelNotice = RegisterLevel(LevelDef{Name: "notice", Severity: xlog.LevelWarn, Color: ColorCyan})
+
+func TestRegisterLevel(t *testing.T) {
+	assert.True(t, levelNotice >= firstCustomLevel)
+	def, ok := levelDef(levelNotice)
+	assert.True(t, ok)
+	assert.Equal(t, "notice", def.Name)
+	assert.Equal(t, "notice", levelName(levelNotice))
+	assert.Equal(t, xlog.LevelWarn, severity(levelNotice))
+	assert.Equal(t, ColorCyan, levelColor("notice"))
+	assert.Panics(t, func() {
+		RegisterLevel(LevelDef{Name: "notice", Severity: xlog.LevelInfo})
+	})
+	assert.Panics(t, func() {
+		RegisterLevel(LevelDef{Name: "info", Severity: xlog.LevelInfo})
+	})
+	assert.Panics(t, func() {
+		RegisterLevel(LevelDef{Name: "invalid", Severity: LevelPanic + 1})
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, level := range map[string]xlog.Level{
+		"trace":  LevelTrace,
+		"debug":  xlog.LevelDebug,
+		"info":   xlog.LevelInfo,
+		"warn":   xlog.LevelWarn,
+		"error":  xlog.LevelError,
+		"fatal":  xlog.LevelFatal,
+		"panic":  LevelPanic,
+		"notice": levelNotice,
+	} {
+		l, err := ParseLevel(name)
+		assert.NoError(t, err)
+		assert.Equal(t, level, l)
+		assert.Equal(t, name, levelName(level))
+	}
+	_, err := ParseLevel("foo")
+	assert.EqualError(t, err, "unknown level: foo")
+}
+
+func TestLevelColor(t *testing.T) {
+	assert.Equal(t, ColorGray, levelColor("trace"))
+	assert.Equal(t, ColorGray, levelColor("debug"))
+	assert.Equal(t, ColorBlue, levelColor("info"))
+	assert.Equal(t, ColorYellow, levelColor("warn"))
+	assert.Equal(t, ColorRed, levelColor("error"))
+	assert.Equal(t, ColorBlue, levelColor("fatal"))
+	assert.Equal(t, ColorRed, levelColor("panic"))
+	assert.Equal(t, ColorBlue, levelColor("foo"))
+}
+
+func TestCustomLevelLogger(t *testing.T) {
+	o := newTestOutput()
+	a := NewAtomicLevel(xlog.LevelError)
+	l := New(Config{AtomicLevel: a, Output: o})
+	l.OutputF(levelNotice, 1, "test", nil)
+	assert.True(t, o.empty())
+	assert.NoError(t, a.UnmarshalText([]byte("notice")))
+	assert.Equal(t, "notice", a.String())
+	assert.False(t, l.Enabled(xlog.LevelInfo))
+	assert.True(t, l.Enabled(xlog.LevelWarn))
+	l.OutputF(levelNotice, 1, "test", nil)
+	assert.Equal(t, "notice", o.get()["level"])
+}
+
+func TestTraceLevel(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o})
+	l.Trace("test")
+	assert.True(t, o.empty())
+	l = New(Config{Level: LevelTrace, Output: o})
+	l.Trace("test")
+	assert.Equal(t, "trace", o.get()["level"])
+	l.Tracef("test %d", 1)
+	assert.Equal(t, "test 1", o.get()["message"])
+	l.TraceEvent().Msg("test")
+	assert.Equal(t, "trace", o.get()["level"])
+}
@@ -0,0 +1,68 @@
+package xlog
+
+import (
+	"github.com/rs/xlog"
+)
+
+// Namespace is a field value holding fields nested under the field key. The JSON
+// and Logstash outputs encode it as a sub-object while the logfmt and console
+// outputs flatten it using dotted keys (i.e.: order.id=42).
+type Namespace map[string]interface{}
+
+// Group implements Logger interface
+func (l *logger) Group(name string) Logger {
+	c := l.with(nil, nil)
+	c.group = append(l.group[:len(l.group):len(l.group)], name)
+	return c
+}
+
+// mergeFields returns a copy of f with fields set in the namespace at the given
+// path. Existing namespaces on the path are copied so f is not modified.
+func mergeFields(f xlog.F, path []string, fields xlog.F) xlog.F {
+	c := make(xlog.F, len(f)+len(fields))
+	for k, v := range f {
+		c[k] = v
+	}
+	if len(path) == 0 {
+		for k, v := range fields {
+			c[k] = v
+		}
+		return c
+	}
+	ns, _ := f[path[0]].(Namespace)
+	c[path[0]] = Namespace(mergeFields(xlog.F(ns), path[1:], fields))
+	return c
+}
+
+// mergeInto sets the fields of src in dst. When both dst and src have a
+// namespace with the same key, the namespaces are merged into a new one with
+// the fields of src taking precedence.
+func mergeInto(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if ns, ok := v.(Namespace); ok {
+			if dns, ok := dst[k].(Namespace); ok {
+				m := make(Namespace, len(dns)+len(ns))
+				mergeInto(m, dns)
+				mergeInto(m, ns)
+				v = m
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// groupFields moves the event fields into the namespace at the given path of the
+// logger fields.
+func (e *Event) groupFields(path []string) {
+	fields := make(xlog.F, len(e.fields))
+	for _, f := range e.fields {
+		fields[f.Key] = f.Value()
+	}
+	ctx := mergeFields(nil, path, fields)
+	mergeInto(ctx, e.ctx)
+	e.ctx = ctx
+	for i := range e.fields {
+		e.fields[i] = Field{}
+	}
+	e.fields = e.fields[:0]
+}
@@ -0,0 +1,130 @@
+package xlog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeFields(t *testing.T) {
+	f := xlog.F{"a": 1, "order": Namespace{"id": 1}}
+	m := mergeFields(f, []string{"order", "item"}, xlog.F{"id": 2})
+	assert.Equal(t, xlog.F{"a": 1, "order": Namespace{"id": 1}}, f)
+	assert.Equal(t, xlog.F{"a": 1, "order": Namespace{"id": 1, "item": Namespace{"id": 2}}}, m)
+	assert.Equal(t, xlog.F{"a": 2}, mergeFields(xlog.F{"a": 1}, nil, xlog.F{"a": 2}))
+}
+
+func TestMergeInto(t *testing.T) {
+	src := xlog.F{"b": 2, "ns": Namespace{"a": 2, "c": 3}}
+	dst := xlog.F{"a": 1, "b": 1, "ns": Namespace{"a": 1, "b": 1}}
+	mergeInto(dst, src)
+	assert.Equal(t, xlog.F{"a": 1, "b": 2, "ns": Namespace{"a": 2, "b": 1, "c": 3}}, dst)
+	assert.Equal(t, Namespace{"a": 2, "c": 3}, src["ns"])
+}
+
+func TestGroup(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, DisableCaller: true, Fields: xlog.F{"id": "user"}})
+	g := l.Group("order").With(xlog.F{"id": "order"})
+	g.SetField("total", 100)
+	g.Info("test", xlog.F{"item": 1, "total": 0})
+	last := o.get()
+	delete(last, KeyTime)
+	assert.Equal(t, map[string]interface{}{
+		"level":   "info",
+		"message": "test",
+		"id":      "user",
+		"order":   Namespace{"id": "order", "total": 100, "item": 1},
+	}, last)
+
+	// Parent is not affected
+	assert.Equal(t, xlog.F{"id": "user"}, l.GetFields())
+
+	g.Group("item").Named("orders").Info("test", xlog.F{"id": 2})
+	last = o.get()
+	assert.Equal(t, "orders", last[KeyLogger])
+	assert.Equal(t, Namespace{"id": "order", "total": 100, "item": Namespace{"id": 2}}, last["order"])
+
+	// Empty groups are omitted
+	l.Group("empty").Info("test")
+	_, found := o.get()["empty"]
+	assert.False(t, found)
+}
+
+func TestGroupEvent(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, DisableCaller: true}).Group("order").With(xlog.F{"id": "order"})
+	l.InfoEvent().Int("item", 1).Str("id", "overridden").Msg("test")
+	last := o.get()
+	assert.Equal(t, "test", last[KeyMessage])
+	assert.Equal(t, Namespace{"id": "order", "item": int64(1)}, last["order"])
+}
+
+func TestGroupEventStack(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, StackTrace: true}).Group("order")
+	l.ErrorEvent().Err(stackErrorOrigin()).Msg("test")
+	last := o.get()
+	assert.Contains(t, last[KeyStack], "stackErrorOrigin")
+	assert.Equal(t, "some error", last["order"].(Namespace)[KeyError].(error).Error())
+}
+
+func TestNamespaceLazyValue(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o}).Group("ns")
+	l.SetField("lazy", LazyValue(func() interface{} { return "val" }))
+	l.Info("test", xlog.F{"card": testCardToken("4242424242424242")})
+	assert.Equal(t, Namespace{"lazy": "val", "card": "****4242"}, o.get()["ns"])
+	assert.IsType(t, LazyValue(nil), l.GetFields()["ns"].(Namespace)["lazy"])
+}
+
+func newNamespaceFields() map[string]interface{} {
+	return map[string]interface{}{
+		"time":    time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC),
+		"level":   "info",
+		"message": "test",
+		"id":      1,
+		"order":   Namespace{"id": 2, "item": Namespace{"id": 3}, "err": errors.New("some error")},
+	}
+}
+
+func TestNamespaceJSONOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, NewJSONOutput(buf).Write(newNamespaceFields()))
+	assert.Equal(t, `{"id":1,"level":"info","message":"test","order":{"err":{"message":"some error","types":["*errors.errorString"]},"id":2,"item":{"id":3}},"time":"2000-01-02T03:04:05Z"}`+"\n", buf.String())
+}
+
+func TestNamespaceLogstashOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	f := newNamespaceFields()
+	delete(f, "order")
+	f["order"] = Namespace{"id": 2, "item": Namespace{"id": 3}}
+	assert.NoError(t, NewLogstashOutput(buf).Write(f))
+	assert.Equal(t, `{"@timestamp":"2000-01-02T03:04:05Z","@version":1,"id":1,"level":"INFO","message":"test","order":{"id":2,"item":{"id":3}}}`, buf.String())
+}
+
+func TestNamespaceLogfmtOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, NewLogfmtOutput(buf).Write(newNamespaceFields()))
+	assert.Equal(t, "level=info message=test time=\"2000-01-02 03:04:05 +0000 UTC\" id=1 order.err=\"some error\" order.id=2 order.item.id=3\n", buf.String())
+}
+
+func TestNamespaceConsoleOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	f := newNamespaceFields()
+	delete(f, "time")
+	assert.NoError(t, consoleOutput{w: buf, keys: DefaultFieldKeys()}.Write(f))
+	assert.Equal(t, "\x1b[34mINFO\x1b[0m test \x1b[32mid\x1b[0m=1 \x1b[32morder.err\x1b[0m=\"some error\" \x1b[32morder.id\x1b[0m=2 \x1b[32morder.item.id\x1b[0m=3\n", buf.String())
+}
+
+func TestNamespaceWriteEvent(t *testing.T) {
+	e := newEncoderTestEvent()
+	e.ctx = xlog.F{"order": Namespace{"id": 2, "item": Namespace{"id": 3}, "err": errors.New("some error")}}
+	for _, written := range assertWriteEvent(t, e) {
+		assert.Contains(t, written, "item")
+	}
+}
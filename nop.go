@@ -1,6 +1,8 @@
 package xlog
 
 import (
+	"context"
+	"fmt"
 	"github.com/rs/xlog"
 	"time"
 )
@@ -14,8 +16,22 @@ func (n nop) SetField(name string, value interface{}) {}
 
 func (n nop) GetFields() xlog.F { return map[string]interface{}{} }
 
+func (n nop) With(fields xlog.F) Logger { return NopLogger }
+
+func (n nop) Named(name string) Logger { return NopLogger }
+
+func (n nop) Group(name string) Logger { return NopLogger }
+
+func (n nop) AddCallerSkip(skip int) Logger { return NopLogger }
+
+func (n nop) Enabled(level xlog.Level) bool { return false }
+
 func (n nop) OutputF(level xlog.Level, calldepth int, msg string, fields map[string]interface{}) {}
 
+func (n nop) Trace(v ...interface{}) {}
+
+func (n nop) Tracef(format string, v ...interface{}) {}
+
 func (n nop) Debug(v ...interface{}) {}
 
 func (n nop) Debugf(format string, v ...interface{}) {}
@@ -40,6 +56,57 @@ func (n nop) Fatalf(format string, v ...interface{}) {
 	exit1()
 }
 
+func (n nop) Panic(v ...interface{}) {
+	extractFields(&v)
+	panic(fmt.Sprint(v...))
+}
+
+func (n nop) Panicf(format string, v ...interface{}) {
+	extractFields(&v)
+	panic(fmt.Sprintf(format, v...))
+}
+
+func (n nop) TraceCtx(ctx context.Context, v ...interface{}) {}
+
+func (n nop) DebugCtx(ctx context.Context, v ...interface{}) {}
+
+func (n nop) InfoCtx(ctx context.Context, v ...interface{}) {}
+
+func (n nop) WarnCtx(ctx context.Context, v ...interface{}) {}
+
+func (n nop) ErrorCtx(ctx context.Context, v ...interface{}) {}
+
+func (n nop) FatalCtx(ctx context.Context, v ...interface{}) {
+	exit1()
+}
+
+func (n nop) PanicCtx(ctx context.Context, v ...interface{}) {
+	extractFields(&v)
+	panic(fmt.Sprint(v...))
+}
+
+func (n nop) TraceEvent() *Event { return nil }
+
+func (n nop) DebugEvent() *Event { return nil }
+
+func (n nop) InfoEvent() *Event { return nil }
+
+func (n nop) WarnEvent() *Event { return nil }
+
+func (n nop) ErrorEvent() *Event { return nil }
+
+func (n nop) FatalEvent() *Event {
+	e := getEvent()
+	e.level = xlog.LevelFatal
+	return e
+}
+
+func (n nop) PanicEvent() *Event {
+	e := getEvent()
+	e.level = LevelPanic
+	return e
+}
+
 func (n nop) Write(p []byte) (int, error) { return len(p), nil }
 
 func (n nop) Output(calldepth int, s string) error {
@@ -1,14 +1,24 @@
 package xlog
 
 import (
-	"github.com/rs/xlog"
+	"context"
 	"testing"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNopLogger(t *testing.T) {
 	// cheap cover score upper
 	NopLogger.SetField("name", "value")
+	NopLogger.With(xlog.F{"name": "value"})
+	NopLogger.Named("name")
+	NopLogger.Group("name")
+	NopLogger.AddCallerSkip(1)
+	NopLogger.Enabled(xlog.LevelFatal)
 	NopLogger.OutputF(xlog.LevelInfo, 0, "", nil)
+	NopLogger.Trace()
+	NopLogger.Tracef("format")
 	NopLogger.Debug()
 	NopLogger.Debugf("format")
 	NopLogger.Info()
@@ -20,6 +30,23 @@ func TestNopLogger(t *testing.T) {
 	exit1 = func() {}
 	NopLogger.Fatal()
 	NopLogger.Fatalf("format")
+	assert.PanicsWithValue(t, "test", func() { NopLogger.Panic("test") })
+	assert.PanicsWithValue(t, "test 1", func() { NopLogger.Panicf("test %d", 1) })
+	assert.PanicsWithValue(t, "test", func() { NopLogger.PanicEvent().Msg("test") })
+	ctx := context.Background()
+	NopLogger.TraceCtx(ctx)
+	NopLogger.DebugCtx(ctx)
+	NopLogger.InfoCtx(ctx)
+	NopLogger.WarnCtx(ctx)
+	NopLogger.ErrorCtx(ctx)
+	NopLogger.FatalCtx(ctx)
+	assert.PanicsWithValue(t, "test", func() { NopLogger.PanicCtx(ctx, "test", xlog.F{"foo": "bar"}) })
+	NopLogger.TraceEvent().Msg("")
+	NopLogger.DebugEvent().Str("foo", "bar").Msg("")
+	NopLogger.InfoEvent().Msg("")
+	NopLogger.WarnEvent().Msg("")
+	NopLogger.ErrorEvent().Msg("")
+	NopLogger.FatalEvent().Msg("")
 	NopLogger.Write([]byte{})
 	NopLogger.Output(0, "")
 }
@@ -2,13 +2,15 @@ package xlog
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
-	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kanmu/xlog/internal/term"
@@ -18,15 +20,122 @@ import (
 
 // OutputChannel is a send buffered channel between xlog and an Output.
 type OutputChannel struct {
-	input  chan map[string]interface{}
-	output xlog.Output
-	stop   chan struct{}
+	input chan message
+	// priority is the reserved buffer of the messages of warn level and above,
+	// nil without reserved capacity
+	priority chan message
+	output   xlog.Output
+	// closeMu is held for reading by the writers while they queue a message and
+	// for writing by Shutdown so no message is queued once the consumer drains
+	// the buffer
+	closeMu sync.RWMutex
+	// closing is closed by Shutdown to release the writers waiting for room
+	closing chan struct{}
+	// stop is closed by Shutdown to have the consumer drain the buffer and exit
+	stop      chan struct{}
+	closeOnce sync.Once
+	// abort is closed by Shutdown when its context is done to have the
+	// consumer exit without writing the buffered messages
+	abort     chan struct{}
+	abortOnce sync.Once
+	// done is closed by the consumer once it exited
+	done   chan struct{}
+	closed uint32
+	// writing is the number of messages taken from the buffer by the consumer
+	// and not written yet
+	writing  uint32
+	overflow OverflowPolicy
+	// keys are the default field keys used to read the level of messages not
+	// sent by a logger and to write the drop summaries
+	keys *FieldKeys
+	// batch is set when messages are written by batches
+	batch         BatchOutput
+	batchSize     int
+	batchInterval time.Duration
+	dropped       uint64
+	drops         dropCounter
+}
+
+// OverflowMode defines what an OutputChannel does when its buffer is full.
+type OverflowMode int
+
+// Overflow modes
+const (
+	// DropNewest discards the new message and returns ErrBufferFull.
+	DropNewest OverflowMode = iota
+	// DropOldest discards the oldest message of the buffer to queue the new one.
+	DropOldest
+	// Block waits for room in the buffer, up to the policy's Timeout if set.
+	Block
+)
+
+// OverflowPolicy defines how an OutputChannel handles a message written while
+// its buffer is full. The zero value drops the new message.
+type OverflowPolicy struct {
+	Mode OverflowMode
+	// Timeout is the maximum time the Block mode waits for room in the buffer.
+	// The message is discarded and ErrBufferFull is returned once it expires.
+	// Zero waits until the output channel is closed.
+	Timeout time.Duration
+	// Reserved is the size of a separate buffer for the messages of warn level
+	// and above, written first by the consumer. They are queued in the shared
+	// buffer once it is full, so a flood of lower level messages can't drop
+	// them while there is room left.
+	Reserved int
+}
+
+// OutputChannelStats holds the counters of an OutputChannel.
+type OutputChannelStats struct {
+	// Queued is the number of messages waiting in the buffer, including the
+	// reserved one.
+	Queued int `json:"queued"`
+	// Capacity is the size of the buffer.
+	Capacity int `json:"capacity"`
+	// Reserved is the size of the buffer reserved to warn level and above.
+	Reserved int `json:"reserved,omitempty"`
+	// Dropped is the number of messages discarded because the buffer was full.
+	Dropped uint64 `json:"dropped"`
+	// DroppedLevels is the number of messages discarded per level name.
+	DroppedLevels map[string]uint64 `json:"dropped_levels,omitempty"`
+}
+
+// message is an entry of the OutputChannel buffer holding either a map or an
+// event with typed fields. The level and keys of maps sent by a logger are set
+// so dropped messages can be counted without parsing their fields.
+type message struct {
+	fields map[string]interface{}
+	event  *Event
+	level  xlog.Level
+	keys   *FieldKeys
 }
 
 // ErrBufferFull is returned when the output channel buffer is full and messages
 // are discarded.
 var ErrBufferFull = errors.New("buffer full")
 
+// ErrOutputClosed is returned when a message is written to an output channel
+// after its shutdown. The message is discarded.
+var ErrOutputClosed = errors.New("output channel closed")
+
+// ShutdownError is returned by OutputChannel.Shutdown when its context is done
+// before all the buffered messages are written.
+type ShutdownError struct {
+	// Lost is the number of messages which were not written, including the
+	// one being written by a hanging output.
+	Lost int
+	// Err is the error of the context.
+	Err error
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("output channel shutdown: %d messages lost: %v", e.Lost, e.Err)
+}
+
+// Unwrap returns the error of the context.
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
 // NewOutputChannel creates a consumer buffered channel for the given output
 // with a default buffer of 100 messages.
 func NewOutputChannel(o xlog.Output) *OutputChannel {
@@ -34,66 +143,315 @@ func NewOutputChannel(o xlog.Output) *OutputChannel {
 }
 
 // NewOutputChannelBuffer creates a consumer buffered channel for the given output
-// with a customizable buffer size.
-func NewOutputChannelBuffer(o xlog.Output, bufSize int) *OutputChannel {
+// with a customizable buffer size. The optional policy defines what happens to
+// messages written while the buffer is full, the new one is dropped by default.
+//
+// With the Block mode, the output must not log on a logger writing to the same
+// output channel as the consumer would wait for itself.
+func NewOutputChannelBuffer(o xlog.Output, bufSize int, policy ...OverflowPolicy) *OutputChannel {
+	oc := newOutputChannel(o, bufSize, policy)
+	go oc.run()
+	return oc
+}
+
+// newOutputChannel returns an output channel without consumer.
+func newOutputChannel(o xlog.Output, bufSize int, policy []OverflowPolicy) *OutputChannel {
 	oc := &OutputChannel{
-		input:  make(chan map[string]interface{}, bufSize),
-		output: o,
-		stop:   make(chan struct{}),
+		input:   make(chan message, bufSize),
+		output:  o,
+		closing: make(chan struct{}),
+		stop:    make(chan struct{}),
+		abort:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	keys := DefaultFieldKeys()
+	oc.keys = &keys
+	if len(policy) > 0 {
+		oc.overflow = policy[0]
 	}
+	if oc.overflow.Reserved > 0 {
+		oc.priority = make(chan message, oc.overflow.Reserved)
+	}
+	return oc
+}
 
-	go func() {
-		for {
+// run is the consumer writing the messages one by one.
+func (oc *OutputChannel) run() {
+	defer close(oc.done)
+	for {
+		var msg message
+		select {
+		case msg = <-oc.priority:
+		default:
 			select {
-			case msg := <-oc.input:
-				if err := o.Write(msg); err != nil {
-					critialLogger.Print("cannot write log message: ", err.Error())
-				}
+			case msg = <-oc.priority:
+			case msg = <-oc.input:
 			case <-oc.stop:
-				close(oc.stop)
+				oc.drain()
 				return
 			}
 		}
-	}()
+		oc.consume(msg)
+		if oc.summaryDue() {
+			oc.writeDropSummary()
+		}
+	}
+}
 
-	return oc
+// summaryDue returns true if the consumer should report the dropped messages:
+// once the buffer is half empty. When closed, the drain reports them unless
+// Shutdown aborted.
+func (oc *OutputChannel) summaryDue() bool {
+	return oc.drops.hasPending() && len(oc.input) <= cap(oc.input)/2 && atomic.LoadUint32(&oc.closed) == 0
 }
 
 // Write implements the Output interface
 func (oc *OutputChannel) Write(fields map[string]interface{}) (err error) {
+	return oc.enqueue(message{fields: fields})
+}
+
+// writeEvent queues the event and takes ownership of it.
+func (oc *OutputChannel) writeEvent(e *Event) error {
+	return oc.enqueue(message{event: e})
+}
+
+func (oc *OutputChannel) enqueue(msg message) (err error) {
+	oc.closeMu.RLock()
+	defer oc.closeMu.RUnlock()
+	if atomic.LoadUint32(&oc.closed) == 1 {
+		oc.drop(msg)
+		return ErrOutputClosed
+	}
+	if oc.priority != nil && msg.urgent(oc.fieldKeys()) {
+		select {
+		case oc.priority <- msg:
+			return nil
+		default:
+			// Reserved buffer is full, fallback on the shared one
+		}
+	}
 	select {
-	case oc.input <- fields:
+	case oc.input <- msg:
 		// Sent with success
+		return nil
 	default:
-		// Channel is full, message dropped
-		err = ErrBufferFull
 	}
-	return err
+	// Channel is full
+	switch oc.overflow.Mode {
+	case Block:
+		return oc.enqueueBlock(msg)
+	case DropOldest:
+		return oc.enqueueDropOldest(msg)
+	}
+	oc.drop(msg)
+	return ErrBufferFull
 }
 
-// Flush flushes all the buffered message to the output
-func (oc *OutputChannel) Flush() {
+// enqueueBlock waits for room in the buffer to queue msg. The message is
+// dropped if the timeout expires or the output channel is closed first. When
+// closed by Shutdown, ErrOutputClosed is returned.
+func (oc *OutputChannel) enqueueBlock(msg message) error {
+	var timeout <-chan time.Time
+	if oc.overflow.Timeout > 0 {
+		t := time.NewTimer(oc.overflow.Timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case oc.input <- msg:
+		return nil
+	case <-timeout:
+	case <-oc.done:
+	case <-oc.closing:
+		oc.drop(msg)
+		return ErrOutputClosed
+	}
+	oc.drop(msg)
+	return ErrBufferFull
+}
+
+// enqueueDropOldest queues msg, discarding the oldest messages of the buffer
+// until it fits. Without buffer, msg is dropped.
+func (oc *OutputChannel) enqueueDropOldest(msg message) error {
+	if cap(oc.input) == 0 {
+		oc.drop(msg)
+		return ErrBufferFull
+	}
 	for {
 		select {
-		case msg := <-oc.input:
-			if err := oc.output.Write(msg); err != nil {
-				critialLogger.Print("cannot write log message: ", err.Error())
-			}
+		case oc.input <- msg:
+			return nil
+		default:
+		}
+		select {
+		case old := <-oc.input:
+			oc.drop(old)
 		default:
+			// The consumer made room meanwhile
+		}
+	}
+}
+
+// drop discards msg and counts it.
+func (oc *OutputChannel) drop(msg message) {
+	name, keys := msg.levelName(oc.fieldKeys())
+	if msg.event != nil {
+		putEvent(msg.event)
+	}
+	atomic.AddUint64(&oc.dropped, 1)
+	oc.drops.add(name, keys)
+}
+
+// consume writes a message taken from the buffer by the consumer, flagging the
+// write so Shutdown can count it as lost if the output hangs.
+func (oc *OutputChannel) consume(msg message) {
+	atomic.StoreUint32(&oc.writing, 1)
+	oc.write(msg)
+	atomic.StoreUint32(&oc.writing, 0)
+}
+
+// drain writes the buffered messages until the buffer is empty or Shutdown
+// aborts, followed by the summary of the dropped messages.
+func (oc *OutputChannel) drain() {
+	for {
+		select {
+		case <-oc.abort:
 			return
+		default:
 		}
+		msg, ok := oc.next()
+		if !ok {
+			if oc.drops.hasPending() {
+				oc.writeDropSummary()
+			}
+			return
+		}
+		oc.consume(msg)
 	}
 }
 
-// Close closes the output channel and release the consumer's go routine.
-func (oc *OutputChannel) Close() {
-	if oc.stop == nil {
+// next takes the next buffered message, from the reserved buffer first. It
+// returns false if both are empty.
+func (oc *OutputChannel) next() (message, bool) {
+	select {
+	case msg := <-oc.priority:
+		return msg, true
+	default:
+	}
+	select {
+	case msg := <-oc.input:
+		return msg, true
+	default:
+		return message{}, false
+	}
+}
+
+// queued returns the number of buffered messages.
+func (oc *OutputChannel) queued() int {
+	return len(oc.input) + len(oc.priority)
+}
+
+// write sends a message taken from the buffer to the output
+func (oc *OutputChannel) write(msg message) {
+	var err error
+	if msg.event != nil {
+		msg.event.resolveLazy(true)
+		err = writeEvent(oc.output, msg.event)
+		putEvent(msg.event)
+	} else {
+		resolveFields(msg.fields, true)
+		err = oc.output.Write(msg.fields)
+	}
+	if err != nil {
+		critialLogger.Print("cannot write log message: ", err.Error())
+	}
+}
+
+// Stats returns the current counters of the output channel.
+func (oc *OutputChannel) Stats() OutputChannelStats {
+	return OutputChannelStats{
+		Queued:        oc.queued(),
+		Capacity:      cap(oc.input),
+		Reserved:      cap(oc.priority),
+		Dropped:       atomic.LoadUint64(&oc.dropped),
+		DroppedLevels: oc.drops.levels(),
+	}
+}
+
+// Flush flushes all the buffered message to the output, followed by the
+// summary of the messages dropped since the last one. In batch mode, the batch
+// being collected by the consumer is not flushed.
+func (oc *OutputChannel) Flush() {
+	if oc.batch != nil {
+		oc.flushBatch()
 		return
 	}
-	oc.stop <- struct{}{}
-	<-oc.stop
-	oc.stop = nil
-	oc.Flush()
+	for {
+		msg, ok := oc.next()
+		if !ok {
+			if oc.drops.hasPending() {
+				oc.writeDropSummary()
+			}
+			return
+		}
+		oc.write(msg)
+	}
+}
+
+// Close closes the output channel and release the consumer's go routine once
+// the buffered messages are written. Use Shutdown to bound the time it waits
+// for a hanging output.
+func (oc *OutputChannel) Close() {
+	oc.Shutdown(context.Background())
+}
+
+// Shutdown closes the output channel, waiting for the consumer's go routine to
+// write the buffered messages until ctx is done. In that case, the messages not
+// written are dropped and a *ShutdownError reporting their count is returned.
+// Messages written after Shutdown was called, including the ones waiting for
+// room with the Block mode, are dropped with ErrOutputClosed.
+//
+// Shutdown can be called several times, from several go routines. Once the
+// consumer exited, it returns nil.
+func (oc *OutputChannel) Shutdown(ctx context.Context) error {
+	if oc.done == nil {
+		// No consumer
+		oc.closeOnce.Do(oc.close)
+		oc.Flush()
+		return nil
+	}
+	oc.closeOnce.Do(func() {
+		oc.close()
+		close(oc.stop)
+	})
+	select {
+	case <-oc.done:
+		return nil
+	case <-ctx.Done():
+	}
+	oc.abortOnce.Do(func() { close(oc.abort) })
+	lost := 0
+	for {
+		msg, ok := oc.next()
+		if !ok {
+			break
+		}
+		oc.drop(msg)
+		lost++
+	}
+	lost += int(atomic.LoadUint32(&oc.writing))
+	return &ShutdownError{Lost: lost, Err: ctx.Err()}
+}
+
+// close marks the output channel closed and waits for the writers which
+// checked it before to queue their message.
+func (oc *OutputChannel) close() {
+	atomic.StoreUint32(&oc.closed, 1)
+	if oc.closing != nil {
+		close(oc.closing)
+	}
+	oc.closeMu.Lock()
+	oc.closeMu.Unlock()
 }
 
 // Discard is an Output that discards all log message going thru it.
@@ -107,6 +465,13 @@ var bufPool = &sync.Pool{
 	},
 }
 
+var bytesPool = &sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 500)
+		return &b
+	},
+}
+
 // FilterOutput test a condition on the message and forward it to the child output
 // if it returns true.
 type FilterOutput struct {
@@ -125,34 +490,64 @@ func (f FilterOutput) Write(fields map[string]interface{}) (err error) {
 }
 
 // LevelOutput routes messages to different output based on the message's level.
+// Messages with a custom level are routed using the severity of the level.
 type LevelOutput struct {
+	// Keys defines the name of the level field. The default name, read once on
+	// first use, is used if empty.
+	Keys  FieldKeys
+	Trace xlog.Output
 	Debug xlog.Output
 	Info  xlog.Output
 	Warn  xlog.Output
 	Error xlog.Output
 	Fatal xlog.Output
+	Panic xlog.Output
 }
 
 func (l LevelOutput) Write(fields map[string]interface{}) error {
-	var o xlog.Output
-	switch fields[KeyLevel] {
-	case "debug":
-		o = l.Debug
-	case "info":
-		o = l.Info
-	case "warn":
-		o = l.Warn
-	case "error":
-		o = l.Error
-	case "fatal":
-		o = l.Fatal
-	}
-	if o != nil {
+	key := l.Keys.Level
+	if key == "" {
+		key = defaultKeys().Level
+	}
+	name, _ := fields[key].(string)
+	level, err := ParseLevel(name)
+	if err != nil {
+		return nil
+	}
+	if o := l.output(level); o != nil {
 		return o.Write(fields)
 	}
 	return nil
 }
 
+// WriteEvent implements the EventOutput interface
+func (l LevelOutput) WriteEvent(e *Event) error {
+	if o := l.output(e.Level()); o != nil {
+		return writeEvent(o, e)
+	}
+	return nil
+}
+
+func (l LevelOutput) output(level xlog.Level) xlog.Output {
+	switch severity(level) {
+	case LevelTrace:
+		return l.Trace
+	case xlog.LevelDebug:
+		return l.Debug
+	case xlog.LevelInfo:
+		return l.Info
+	case xlog.LevelWarn:
+		return l.Warn
+	case xlog.LevelError:
+		return l.Error
+	case xlog.LevelFatal:
+		return l.Fatal
+	case LevelPanic:
+		return l.Panic
+	}
+	return nil
+}
+
 // RecorderOutput stores the raw messages in it's Messages field. This output is useful for testing.
 type RecorderOutput struct {
 	Messages []xlog.F
@@ -173,7 +568,8 @@ func (l *RecorderOutput) Reset() {
 }
 
 type consoleOutput struct {
-	w io.Writer
+	w    io.Writer
+	keys FieldKeys
 }
 
 var isTerminal = term.IsTerminal
@@ -187,8 +583,13 @@ func NewConsoleOutput() xlog.Output {
 // NewConsoleOutputW returns a Output printing message in a colored human readable form with
 // the provided writer. If the writer is not on a terminal, the noTerm output is returned.
 func NewConsoleOutputW(w io.Writer, noTerm xlog.Output) xlog.Output {
+	return NewConsoleOutputKeys(w, noTerm, DefaultFieldKeys())
+}
+
+// NewConsoleOutputKeys is like NewConsoleOutputW but reads the fields named by keys.
+func NewConsoleOutputKeys(w io.Writer, noTerm xlog.Output, keys FieldKeys) xlog.Output {
 	if isTerminal(w) {
-		return consoleOutput{w: w}
+		return consoleOutput{w: w, keys: keys.withDefaults()}
 	}
 	return noTerm
 }
@@ -199,58 +600,52 @@ func (o consoleOutput) Write(fields map[string]interface{}) error {
 		buf.Reset()
 		bufPool.Put(buf)
 	}()
-	if ts, ok := fields[KeyTime].(time.Time); ok {
+	keys := &o.keys
+	if ts, ok := fields[keys.Time].(time.Time); ok {
 		buf.Write([]byte(ts.Format("2006/01/02 15:04:05 ")))
 	}
-	if lvl, ok := fields[KeyLevel].(string); ok {
-		levelColor := blue
-		switch lvl {
-		case "debug":
-			levelColor = gray
-		case "warn":
-			levelColor = yellow
-		case "error":
-			levelColor = red
+	if lvl, ok := fields[keys.Level].(string); ok {
+		name := strings.ToUpper(lvl)
+		if len(name) > 4 {
+			name = name[0:4]
+		} else if len(name) < 4 {
+			name += strings.Repeat(" ", 4-len(name))
 		}
-		colorPrint(buf, strings.ToUpper(lvl[0:4]), levelColor)
+		colorPrint(buf, name, levelColor(lvl))
 		buf.WriteByte(' ')
 	}
-	if msg, ok := fields[KeyMessage].(string); ok {
+	if msg, ok := fields[keys.Message].(string); ok {
 		msg = strings.Replace(msg, "\n", "\\n", -1)
 		buf.Write([]byte(msg))
 	}
-	// Gather field keys
-	keys := []string{}
-	for k := range fields {
-		switch k {
-		case KeyLevel, KeyMessage, KeyTime:
-			continue
-		}
-		keys = append(keys, k)
-	}
-	// Sort fields by key names
-	sort.Strings(keys)
+	stack, hasStack := stackValue(fields[keys.Stack])
 	// Print fields using logfmt format
-	for _, k := range keys {
-		buf.WriteByte(' ')
-		colorPrint(buf, k, green)
-		buf.WriteByte('=')
-		if err := writeValue(buf, fields[k]); err != nil {
+	for _, k := range keys.sortedKeys(fields, hasStack) {
+		if err := writeKeyValue(buf, k, fields[k], true); err != nil {
 			return err
 		}
 	}
 	buf.WriteByte('\n')
+	if hasStack {
+		writeStack(buf, stack)
+	}
 	_, err := o.w.Write(buf.Bytes())
 	return err
 }
 
 type logfmtOutput struct {
-	w io.Writer
+	w    io.Writer
+	keys FieldKeys
 }
 
 // NewLogfmtOutput returns a new output using logstash JSON schema v1
 func NewLogfmtOutput(w io.Writer) xlog.Output {
-	return logfmtOutput{w: w}
+	return NewLogfmtOutputKeys(w, DefaultFieldKeys())
+}
+
+// NewLogfmtOutputKeys is like NewLogfmtOutput but reads the fields named by keys.
+func NewLogfmtOutputKeys(w io.Writer, keys FieldKeys) xlog.Output {
+	return logfmtOutput{w: w, keys: keys.withDefaults()}
 }
 
 func (o logfmtOutput) Write(fields map[string]interface{}) error {
@@ -259,62 +654,146 @@ func (o logfmtOutput) Write(fields map[string]interface{}) error {
 		buf.Reset()
 		bufPool.Put(buf)
 	}()
-	// Gather field keys
-	keys := []string{}
-	for k := range fields {
-		switch k {
-		case KeyLevel, KeyMessage, KeyTime:
-			continue
+	fk := &o.keys
+	stack, hasStack := stackValue(fields[fk.Stack])
+	keys := fk.sortedKeys(fields, hasStack)
+	// Write default fields in a specific order
+	for i, k := range []string{fk.Level, fk.Message, fk.Time} {
+		if i > 0 {
+			buf.WriteByte(' ')
 		}
-		keys = append(keys, k)
-	}
-	// Sort fields by key names
-	sort.Strings(keys)
-	// Prepend default fields in a specific order
-	keys = append([]string{KeyLevel, KeyMessage, KeyTime}, keys...)
-	l := len(keys)
-	for i, k := range keys {
 		buf.Write([]byte(k))
 		buf.WriteByte('=')
 		if err := writeValue(buf, fields[k]); err != nil {
 			return err
 		}
-		if i+1 < l {
+	}
+	for _, k := range keys {
+		if err := writeKeyValue(buf, k, fields[k], false); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('\n')
+	// Write the stack trace on its own lines after the message so it stays readable
+	if hasStack {
+		writeStack(buf, stack)
+	}
+	_, err := o.w.Write(buf.Bytes())
+	return err
+}
+
+// WriteEvent implements the EventOutput interface
+func (o logfmtOutput) WriteEvent(e *Event) error {
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		bufPool.Put(buf)
+	}()
+	fields := e.sortedFields()
+	// Write default fields in a specific order
+	keys := &o.keys
+	for i, k := range []string{keys.Level, keys.Message, keys.Time} {
+		if i > 0 {
 			buf.WriteByte(' ')
-		} else {
-			buf.WriteByte('\n')
 		}
+		buf.Write([]byte(k))
+		buf.WriteByte('=')
+		if err := writeField(buf, findField(fields, k)); err != nil {
+			return err
+		}
+	}
+	stack, hasStack := stackField(findField(fields, keys.Stack))
+	for _, f := range fields {
+		switch f.Key {
+		case keys.Level, keys.Message, keys.Time:
+			continue
+		case keys.Stack:
+			if hasStack {
+				continue
+			}
+		}
+		if f.Interface != nil {
+			if err := writeKeyValue(buf, f.Key, f.Interface, false); err != nil {
+				return err
+			}
+			continue
+		}
+		buf.WriteByte(' ')
+		buf.Write([]byte(f.Key))
+		buf.WriteByte('=')
+		if err := writeField(buf, f); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('\n')
+	if hasStack {
+		writeStack(buf, stack)
 	}
 	_, err := o.w.Write(buf.Bytes())
 	return err
 }
 
+type jsonOutput struct {
+	w   io.Writer
+	enc *json.Encoder
+}
+
 // NewJSONOutput returns a new JSON output with the given writer.
 func NewJSONOutput(w io.Writer) xlog.Output {
-	enc := json.NewEncoder(w)
-	return xlog.OutputFunc(func(fields map[string]interface{}) error {
-		return enc.Encode(fields)
-	})
+	return jsonOutput{w: w, enc: json.NewEncoder(w)}
+}
+
+func (o jsonOutput) Write(fields map[string]interface{}) error {
+	return o.enc.Encode(withErrorObjects(fields))
+}
+
+// WriteEvent implements the EventOutput interface
+func (o jsonOutput) WriteEvent(e *Event) error {
+	bp := bytesPool.Get().(*[]byte)
+	defer bytesPool.Put(bp)
+	b := append((*bp)[:0], '{')
+	for i, f := range e.sortedFields() {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, f.Key)
+		b = append(b, ':')
+		var err error
+		if b, err = appendJSONField(b, f); err != nil {
+			return err
+		}
+	}
+	b = append(b, '}', '\n')
+	*bp = b
+	_, err := o.w.Write(b)
+	return err
 }
 
 // NewLogstashOutput returns an output to generate logstash friendly JSON format.
 func NewLogstashOutput(w io.Writer) xlog.Output {
+	return NewLogstashOutputKeys(w, DefaultFieldKeys())
+}
+
+// NewLogstashOutputKeys is like NewLogstashOutput but reads the fields named by keys.
+func NewLogstashOutputKeys(w io.Writer, keys FieldKeys) xlog.Output {
+	keys = keys.withDefaults()
 	return xlog.OutputFunc(func(fields map[string]interface{}) error {
 		lsf := map[string]interface{}{
 			"@version": 1,
 		}
-		for k, v := range fields {
+		for k, v := range withErrorObjects(fields) {
 			switch k {
-			case KeyTime:
+			case keys.Time:
 				k = "@timestamp"
-			case KeyLevel:
+			case keys.Level:
 				if s, ok := v.(string); ok {
 					v = strings.ToUpper(s)
 				}
 			}
-			if t, ok := v.(time.Time); ok {
+			switch t := v.(type) {
+			case time.Time:
 				lsf[k] = t.Format(time.RFC3339)
-			} else {
+			default:
 				lsf[k] = v
 			}
 		}
@@ -0,0 +1,189 @@
+package xlog
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/xlog"
+)
+
+// BatchOutput is an output writing several messages in one call, like a network
+// sink sending them in a single request. Use it with NewBatchOutputChannel.
+//
+// The messages slice is reused once WriteBatch returns and must not be retained.
+type BatchOutput interface {
+	WriteBatch(messages []map[string]interface{}) error
+}
+
+type batchAdapter struct {
+	o xlog.Output
+}
+
+// NewBatchAdapter returns o if it implements BatchOutput or a BatchOutput
+// writing the messages to o one by one. If one or more writes return an error,
+// the last error is returned.
+func NewBatchAdapter(o xlog.Output) BatchOutput {
+	if bo, ok := o.(BatchOutput); ok {
+		return bo
+	}
+	return batchAdapter{o: o}
+}
+
+func (a batchAdapter) Write(fields map[string]interface{}) error {
+	return a.o.Write(fields)
+}
+
+// WriteBatch implements the BatchOutput interface
+func (a batchAdapter) WriteBatch(messages []map[string]interface{}) (err error) {
+	for _, m := range messages {
+		if e := a.o.Write(m); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// NewBatchOutputChannel creates a consumer buffered channel for the given output
+// writing the messages by batches of up to batchSize messages. A batch is
+// written once full or interval after its first message was collected. With a
+// zero interval, it is written as soon as the buffer is empty. The output is
+// adapted with NewBatchAdapter if it does not implement BatchOutput.
+func NewBatchOutputChannel(o xlog.Output, bufSize, batchSize int, interval time.Duration, policy ...OverflowPolicy) *OutputChannel {
+	oc := newOutputChannel(o, bufSize, policy)
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	oc.batch = NewBatchAdapter(o)
+	oc.batchSize = batchSize
+	oc.batchInterval = interval
+	go oc.runBatch()
+	return oc
+}
+
+// runBatch is the consumer writing the messages by batches.
+func (oc *OutputChannel) runBatch() {
+	defer close(oc.done)
+	batch := make([]map[string]interface{}, 0, oc.batchSize+1)
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	for {
+		var msg message
+		select {
+		case msg = <-oc.priority:
+		default:
+			select {
+			case msg = <-oc.priority:
+			case msg = <-oc.input:
+			case <-timeout:
+				timer, timeout = nil, nil
+				batch = oc.writeBatch(batch)
+				continue
+			case <-oc.stop:
+				if timer != nil {
+					timer.Stop()
+				}
+				oc.drainBatch(batch)
+				return
+			}
+		}
+		batch = oc.collect(batch, msg)
+		if oc.summaryDue() {
+			if f := oc.dropSummaryFields(); f != nil {
+				batch = append(batch, f)
+			}
+		}
+		if len(batch) >= oc.batchSize || (oc.batchInterval <= 0 && oc.queued() == 0) {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			batch = oc.writeBatch(batch)
+		} else if timer == nil && oc.batchInterval > 0 {
+			timer = time.NewTimer(oc.batchInterval)
+			timeout = timer.C
+		}
+	}
+}
+
+// collect appends the fields of msg, taken from the buffer, to batch.
+func (oc *OutputChannel) collect(batch []map[string]interface{}, msg message) []map[string]interface{} {
+	batch = append(batch, msg.resolve())
+	atomic.StoreUint32(&oc.writing, uint32(len(batch)))
+	return batch
+}
+
+// resolve returns the fields of a message taken from the buffer, releasing its
+// event if any.
+func (msg message) resolve() map[string]interface{} {
+	if msg.event == nil {
+		resolveFields(msg.fields, true)
+		return msg.fields
+	}
+	msg.event.resolveLazy(true)
+	fields := msg.event.Fields()
+	putEvent(msg.event)
+	return fields
+}
+
+// writeBatch writes batch to the output and returns it emptied for reuse.
+func (oc *OutputChannel) writeBatch(batch []map[string]interface{}) []map[string]interface{} {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := oc.batch.WriteBatch(batch); err != nil {
+		critialLogger.Print("cannot write log messages: ", err.Error())
+	}
+	atomic.StoreUint32(&oc.writing, 0)
+	for i := range batch {
+		batch[i] = nil
+	}
+	return batch[:0]
+}
+
+// drainBatch writes batch and the buffered messages by batches until the
+// buffer is empty or Shutdown aborts, followed by the summary of the dropped
+// messages.
+func (oc *OutputChannel) drainBatch(batch []map[string]interface{}) {
+	for {
+		select {
+		case <-oc.abort:
+			return
+		default:
+		}
+		for len(batch) < oc.batchSize {
+			msg, ok := oc.next()
+			if !ok {
+				break
+			}
+			batch = oc.collect(batch, msg)
+		}
+		if len(batch) == 0 {
+			if f := oc.dropSummaryFields(); f != nil {
+				oc.writeBatch(append(batch, f))
+			}
+			return
+		}
+		batch = oc.writeBatch(batch)
+	}
+}
+
+// flushBatch writes the buffered messages, followed by the summary of the
+// dropped messages, in one batch.
+func (oc *OutputChannel) flushBatch() {
+	var batch []map[string]interface{}
+	for {
+		msg, ok := oc.next()
+		if !ok {
+			break
+		}
+		batch = append(batch, msg.resolve())
+	}
+	if f := oc.dropSummaryFields(); f != nil {
+		batch = append(batch, f)
+	}
+	if len(batch) > 0 {
+		if err := oc.batch.WriteBatch(batch); err != nil {
+			critialLogger.Print("cannot write log messages: ", err.Error())
+		}
+	}
+}
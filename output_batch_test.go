@@ -0,0 +1,168 @@
+package xlog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type testBatchOutput struct {
+	testOutput
+	batches chan []map[string]interface{}
+}
+
+func newTestBatchOutput() *testBatchOutput {
+	return &testBatchOutput{
+		testOutput: *newTestOutput(),
+		batches:    make(chan []map[string]interface{}, 10),
+	}
+}
+
+func (o *testBatchOutput) WriteBatch(messages []map[string]interface{}) error {
+	// The slice is reused by the caller
+	o.batches <- append([]map[string]interface{}{}, messages...)
+	return o.err
+}
+
+func (o *testBatchOutput) getBatch() []interface{} {
+	select {
+	case batch := <-o.batches:
+		ids := []interface{}{}
+		for _, m := range batch {
+			ids = append(ids, m["id"])
+		}
+		return ids
+	case <-time.After(2 * time.Second):
+		return nil
+	}
+}
+
+func TestBatchAdapter(t *testing.T) {
+	bo := newTestBatchOutput()
+	assert.Equal(t, bo, NewBatchAdapter(bo))
+
+	o := newTestOutput()
+	a := NewBatchAdapter(o)
+	assert.NoError(t, a.WriteBatch([]map[string]interface{}{{"id": 1}, {"id": 2}}))
+	assert.Equal(t, 1, o.get()["id"])
+	assert.Equal(t, 2, o.get()["id"])
+	assert.True(t, o.empty())
+}
+
+func TestBatchAdapterError(t *testing.T) {
+	o := newTestOutputErr(errors.New("some error"))
+	a := NewBatchAdapter(o)
+	err := a.WriteBatch([]map[string]interface{}{{"id": 1}, {"id": 2}})
+	assert.EqualError(t, err, "some error")
+	// All the messages are written despite the error
+	assert.Equal(t, 1, o.get()["id"])
+	assert.Equal(t, 2, o.get()["id"])
+}
+
+func TestBatchOutputChannelSize(t *testing.T) {
+	o := newTestBatchOutput()
+	oc := NewBatchOutputChannel(o, 10, 3, time.Hour)
+	defer oc.Close()
+	for i := 1; i <= 6; i++ {
+		assert.NoError(t, oc.Write(xlog.F{"id": i}))
+	}
+	assert.Equal(t, []interface{}{1, 2, 3}, o.getBatch())
+	assert.Equal(t, []interface{}{4, 5, 6}, o.getBatch())
+}
+
+func TestBatchOutputChannelInterval(t *testing.T) {
+	o := newTestBatchOutput()
+	oc := NewBatchOutputChannel(o, 10, 100, 10*time.Millisecond)
+	defer oc.Close()
+	assert.NoError(t, oc.Write(xlog.F{"id": 1}))
+	assert.NoError(t, oc.Write(xlog.F{"id": 2}))
+	assert.Equal(t, []interface{}{1, 2}, o.getBatch())
+	assert.NoError(t, oc.Write(xlog.F{"id": 3}))
+	assert.Equal(t, []interface{}{3}, o.getBatch())
+}
+
+func TestBatchOutputChannelNoInterval(t *testing.T) {
+	o := newTestBatchOutput()
+	oc := NewBatchOutputChannel(o, 10, 100, 0)
+	defer oc.Close()
+	assert.NoError(t, oc.Write(xlog.F{"id": 1}))
+	assert.Equal(t, []interface{}{1}, o.getBatch())
+}
+
+func TestBatchOutputChannelPlainOutput(t *testing.T) {
+	o := newTestOutput()
+	oc := NewBatchOutputChannel(o, 10, 2, time.Hour)
+	defer oc.Close()
+	for i := 1; i <= 2; i++ {
+		assert.NoError(t, oc.Write(xlog.F{"id": i}))
+	}
+	assert.Equal(t, 1, o.get()["id"])
+	assert.Equal(t, 2, o.get()["id"])
+}
+
+func TestBatchOutputChannelEvent(t *testing.T) {
+	o := newTestBatchOutput()
+	oc := NewBatchOutputChannel(o, 10, 1, time.Hour)
+	defer oc.Close()
+	l := New(Config{Output: oc}).(*logger)
+	l.Info("foo", xlog.F{"id": 1})
+	batch := <-o.batches
+	assert.Len(t, batch, 1)
+	assert.Equal(t, 1, batch[0]["id"])
+	assert.Equal(t, "foo", batch[0]["message"])
+	assert.Equal(t, "info", batch[0]["level"])
+}
+
+func TestBatchOutputChannelShutdown(t *testing.T) {
+	o := newTestBatchOutput()
+	oc := NewBatchOutputChannel(o, 10, 2, time.Hour)
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, oc.Write(xlog.F{"id": i}))
+	}
+	assert.NoError(t, oc.Shutdown(context.Background()))
+	var ids []interface{}
+	for len(o.batches) > 0 {
+		batch := o.getBatch()
+		assert.True(t, len(batch) <= 2)
+		ids = append(ids, batch...)
+	}
+	assert.Equal(t, []interface{}{1, 2, 3, 4, 5}, ids)
+}
+
+func TestBatchOutputChannelFlush(t *testing.T) {
+	o := newTestBatchOutput()
+	oc := &OutputChannel{input: make(chan message, 10), batch: o, batchSize: 2}
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, oc.Write(xlog.F{"id": i}))
+	}
+	oc.Flush()
+	assert.Equal(t, []interface{}{1, 2, 3}, o.getBatch())
+	assert.Equal(t, 0, len(oc.input))
+}
+
+func TestBatchOutputChannelDropSummary(t *testing.T) {
+	o := newTestBatchOutput()
+	oc := &OutputChannel{input: make(chan message, 1), batch: o, batchSize: 10}
+	assert.NoError(t, oc.Write(xlog.F{"id": 1}))
+	assert.Equal(t, ErrBufferFull, oc.Write(xlog.F{"id": 2}))
+	oc.Flush()
+	batch := <-o.batches
+	assert.Len(t, batch, 2)
+	assert.Equal(t, 1, batch[0]["id"])
+	assert.Equal(t, "dropped 1 message: 1 unknown", batch[1]["message"])
+}
+
+func TestBatchOutputChannelReserved(t *testing.T) {
+	o := newTestBatchOutput()
+	oc := newOutputChannel(o, 10, []OverflowPolicy{{Reserved: 10}})
+	oc.batch = o
+	oc.batchSize = 10
+	assert.NoError(t, oc.Write(xlog.F{"level": "debug", "id": 1}))
+	assert.NoError(t, oc.Write(xlog.F{"level": "error", "id": 2}))
+	oc.Flush()
+	assert.Equal(t, []interface{}{2, 1}, o.getBatch())
+}
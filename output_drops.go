@@ -0,0 +1,191 @@
+package xlog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/xlog"
+)
+
+// unknownLevel is the name under which messages without a level are counted.
+const unknownLevel = "unknown"
+
+// dropCounter counts the messages dropped by an OutputChannel per level.
+type dropCounter struct {
+	mu sync.Mutex
+	// total holds the counts since the creation of the output channel
+	total map[string]uint64
+	// pending holds the counts since the last summary
+	pending map[string]uint64
+	// keys are the field keys of the last dropped message sent by a logger,
+	// used to write the summary
+	keys *FieldKeys
+	// hasDrops is set to 1 when pending is not empty so the consumer can check
+	// it without taking the lock
+	hasDrops uint32
+}
+
+// add counts a dropped message of the level name.
+func (c *dropCounter) add(name string, keys *FieldKeys) {
+	c.mu.Lock()
+	if c.total == nil {
+		c.total = map[string]uint64{}
+	}
+	if c.pending == nil {
+		c.pending = map[string]uint64{}
+	}
+	c.total[name]++
+	c.pending[name]++
+	if keys != nil {
+		c.keys = keys
+	}
+	atomic.StoreUint32(&c.hasDrops, 1)
+	c.mu.Unlock()
+}
+
+// hasPending returns true if messages were dropped since the last summary.
+func (c *dropCounter) hasPending() bool {
+	return atomic.LoadUint32(&c.hasDrops) == 1
+}
+
+// takePending returns and resets the counts since the last summary.
+func (c *dropCounter) takePending() (map[string]uint64, *FieldKeys) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.pending
+	c.pending = nil
+	atomic.StoreUint32(&c.hasDrops, 0)
+	return p, c.keys
+}
+
+// levels returns a copy of the counts since the creation of the output channel
+// or nil if no message was dropped.
+func (c *dropCounter) levels() map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.total) == 0 {
+		return nil
+	}
+	m := make(map[string]uint64, len(c.total))
+	for k, v := range c.total {
+		m[k] = v
+	}
+	return m
+}
+
+// levelName returns the name of the level of msg and the field keys of the
+// logger which sent it, if known. The level of messages not sent by a logger is
+// read from the field named by keys.
+func (msg message) levelName(keys *FieldKeys) (string, *FieldKeys) {
+	if msg.event != nil {
+		return levelName(msg.event.level), msg.event.keys
+	}
+	if msg.keys != nil {
+		return levelName(msg.level), msg.keys
+	}
+	if name, ok := msg.fields[keys.Level].(string); ok && name != "" {
+		return name, nil
+	}
+	return unknownLevel, nil
+}
+
+// urgent returns true if msg is of warn level or above and goes to the reserved
+// buffer. The level of messages not sent by a logger is read from the field
+// named by keys.
+func (msg message) urgent(keys *FieldKeys) bool {
+	var level xlog.Level
+	switch {
+	case msg.event != nil:
+		level = msg.event.level
+	case msg.keys != nil:
+		level = msg.level
+	default:
+		name, _ := msg.levelName(keys)
+		l, err := ParseLevel(name)
+		if err != nil {
+			return false
+		}
+		level = l
+	}
+	return severity(level) >= xlog.LevelWarn
+}
+
+// byLevel sorts level names by severity, unknown levels last and by name.
+type byLevel []string
+
+func (b byLevel) Len() int      { return len(b) }
+func (b byLevel) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byLevel) Less(i, j int) bool {
+	ri, rj := levelRank(b[i]), levelRank(b[j])
+	if ri != rj {
+		return ri < rj
+	}
+	return b[i] < b[j]
+}
+
+// levelRank returns the severity of the level name, above LevelPanic if unknown.
+func levelRank(name string) xlog.Level {
+	if l, err := ParseLevel(name); err == nil {
+		return severity(l)
+	}
+	return LevelPanic + 1
+}
+
+// dropSummary returns the message reporting the counts of dropped messages,
+// i.e.: "dropped 1532 messages: 1500 debug, 32 info". Levels are sorted by
+// severity, unknown ones last.
+func dropSummary(counts map[string]uint64) string {
+	names := make([]string, 0, len(counts))
+	var total uint64
+	for name, n := range counts {
+		names = append(names, name)
+		total += n
+	}
+	sort.Sort(byLevel(names))
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%d %s", counts[name], name)
+	}
+	noun := "messages"
+	if total == 1 {
+		noun = "message"
+	}
+	return fmt.Sprintf("dropped %d %s: %s", total, noun, strings.Join(parts, ", "))
+}
+
+// fieldKeys returns the default field keys of the output channel.
+func (oc *OutputChannel) fieldKeys() *FieldKeys {
+	if oc.keys == nil {
+		return defaultKeys()
+	}
+	return oc.keys
+}
+
+// writeDropSummary writes a warning reporting the messages dropped since the
+// last summary to the output.
+func (oc *OutputChannel) writeDropSummary() {
+	if f := oc.dropSummaryFields(); f != nil {
+		oc.write(message{fields: f})
+	}
+}
+
+// dropSummaryFields returns the warning reporting the messages dropped since the
+// last summary or nil if none were.
+func (oc *OutputChannel) dropSummaryFields() map[string]interface{} {
+	counts, keys := oc.drops.takePending()
+	if len(counts) == 0 {
+		return nil
+	}
+	if keys == nil {
+		keys = oc.fieldKeys()
+	}
+	return map[string]interface{}{
+		keys.Time:    time.Now(),
+		keys.Level:   levelName(xlog.LevelWarn),
+		keys.Message: dropSummary(counts),
+	}
+}
@@ -0,0 +1,123 @@
+package xlog
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDropSummary(t *testing.T) {
+	assert.Equal(t, "dropped 1 message: 1 info", dropSummary(map[string]uint64{"info": 1}))
+	assert.Equal(t, "dropped 1532 messages: 1500 debug, 32 info",
+		dropSummary(map[string]uint64{"info": 32, "debug": 1500}))
+	assert.Equal(t, "dropped 7 messages: 1 trace, 2 notice, 1 warn, 1 panic, 1 foo, 1 unknown",
+		dropSummary(map[string]uint64{"unknown": 1, "panic": 1, "notice": 2, "warn": 1, "trace": 1, "foo": 1}))
+}
+
+func TestMessageLevelName(t *testing.T) {
+	keys := FieldKeys{Level: "severity"}.withDefaults()
+	name, k := message{fields: xlog.F{"level": "info"}, level: xlog.LevelDebug, keys: &keys}.levelName(&keys)
+	assert.Equal(t, "debug", name)
+	assert.Equal(t, &keys, k)
+	name, k = message{fields: xlog.F{"level": "warn"}}.levelName(defaultKeys())
+	assert.Equal(t, "warn", name)
+	assert.Nil(t, k)
+	// Plain maps are read with the given keys
+	name, _ = message{fields: xlog.F{"level": "warn"}}.levelName(&keys)
+	assert.Equal(t, "unknown", name)
+	name, _ = message{fields: xlog.F{"severity": "warn"}}.levelName(&keys)
+	assert.Equal(t, "warn", name)
+	name, _ = message{fields: xlog.F{}}.levelName(&keys)
+	assert.Equal(t, "unknown", name)
+	name, k = message{event: &Event{level: xlog.LevelError, keys: &keys}}.levelName(&keys)
+	assert.Equal(t, "error", name)
+	assert.Equal(t, &keys, k)
+}
+
+func TestOutputChannelDropCounts(t *testing.T) {
+	oc := &OutputChannel{input: make(chan message, 1), output: newTestOutput()}
+	l := New(Config{Output: oc, Level: xlog.LevelDebug, DisableCaller: true}).(*logger)
+	l.Info("queued")
+	l.Debug("dropped")
+	l.Debug("dropped")
+	l.InfoEvent().Msg("dropped")
+	oc.Write(xlog.F{"message": "dropped"})
+	assert.Equal(t, OutputChannelStats{
+		Queued:        1,
+		Capacity:      1,
+		Dropped:       4,
+		DroppedLevels: map[string]uint64{"debug": 2, "info": 1, "unknown": 1},
+	}, oc.Stats())
+}
+
+func TestOutputChannelDropSummary(t *testing.T) {
+	o := newTestOutput()
+	oc := &OutputChannel{input: make(chan message, 1), output: o}
+	keys := FieldKeys{Message: "msg"}
+	l := New(Config{Output: oc, Level: xlog.LevelDebug, DisableCaller: true, FieldKeys: keys}).(*logger)
+	l.Info("queued")
+	for i := 0; i < 1500; i++ {
+		l.Debug("dropped")
+	}
+	for i := 0; i < 32; i++ {
+		l.Info("dropped")
+	}
+	oc.Flush()
+	assert.Equal(t, "queued", o.get()["msg"])
+	last := o.get()
+	assert.Equal(t, "warn", last[KeyLevel])
+	assert.Equal(t, "dropped 1532 messages: 1500 debug, 32 info", last["msg"])
+	assert.Contains(t, last, KeyTime)
+	assert.True(t, o.empty())
+
+	// The summary only reports the messages dropped since the previous one
+	l.Info("queued")
+	l.Warn("dropped")
+	oc.Flush()
+	o.get()
+	assert.Equal(t, "dropped 1 message: 1 warn", o.get()["msg"])
+	oc.Flush()
+	assert.True(t, o.empty())
+	assert.Equal(t, map[string]uint64{"debug": 1500, "info": 32, "warn": 1}, oc.Stats().DroppedLevels)
+}
+
+func TestOutputChannelDropSummaryConsumer(t *testing.T) {
+	o := gateOutput{gate: make(chan struct{}), o: newTestOutput()}
+	oc := NewOutputChannelBuffer(o, 2)
+	defer oc.Close()
+	l := New(Config{Output: oc, DisableCaller: true}).(*logger)
+	// The first message is held by the consumer and the two next fill the buffer
+	l.Info("1")
+	for len(oc.input) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	l.Info("2")
+	l.Info("3")
+	l.Error("dropped")
+	close(o.gate)
+	// The summary is written once the buffer is half empty
+	for _, msg := range []string{"1", "2", "dropped 1 message: 1 error", "3"} {
+		assert.Equal(t, msg, o.o.get()[KeyMessage])
+	}
+}
+
+func TestSendDropNoStderr(t *testing.T) {
+	buf := &bytes.Buffer{}
+	critialLoggerMux.Lock()
+	oldCritialLogger := critialLogger
+	critialLogger = log.New(buf, "", 0)
+	defer func() {
+		critialLogger = oldCritialLogger
+		critialLoggerMux.Unlock()
+	}()
+	oc := &OutputChannel{input: make(chan message)}
+	l := New(Config{Output: oc}).(*logger)
+	l.Info("dropped")
+	l.InfoEvent().Msg("dropped")
+	assert.Equal(t, uint64(2), oc.Stats().Dropped)
+	assert.Equal(t, "", buf.String())
+}
@@ -19,11 +19,16 @@ func NewSyslogOutput(network, address, tag string) xlog.Output {
 // with the proper priority added to the passed facility.
 // If network and address are empty, Dial will connect to the local syslog server.
 func NewSyslogOutputFacility(network, address, tag string, facility syslog.Priority) xlog.Output {
+	debug := NewJSONOutput(NewSyslogWriter(network, address, facility|syslog.LOG_DEBUG, tag))
+	crit := NewJSONOutput(NewSyslogWriter(network, address, facility|syslog.LOG_CRIT, tag))
 	o := LevelOutput{
-		Debug: NewJSONOutput(NewSyslogWriter(network, address, facility|syslog.LOG_DEBUG, tag)),
+		Trace: debug,
+		Debug: debug,
 		Info:  NewJSONOutput(NewSyslogWriter(network, address, facility|syslog.LOG_INFO, tag)),
 		Warn:  NewJSONOutput(NewSyslogWriter(network, address, facility|syslog.LOG_WARNING, tag)),
 		Error: NewJSONOutput(NewSyslogWriter(network, address, facility|syslog.LOG_ERR, tag)),
+		Fatal: crit,
+		Panic: crit,
 	}
 	return o
 }
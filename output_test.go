@@ -2,11 +2,14 @@ package xlog
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -58,7 +61,7 @@ func TestOutputChannel(t *testing.T) {
 	o := newTestOutput()
 	oc := NewOutputChannel(o)
 	defer oc.Close()
-	oc.input <- xlog.F{"foo": "bar"}
+	oc.Write(xlog.F{"foo": "bar"})
 	assert.Equal(t, xlog.F{"foo": "bar"}, xlog.F(o.get()))
 }
 
@@ -72,7 +75,7 @@ func TestOutputChannelError(t *testing.T) {
 		critialLogger = log.New(w, "", 0)
 		o := newTestOutputErr(errors.New("some error"))
 		oc := NewOutputChannel(o)
-		oc.input <- xlog.F{"foo": "bar"}
+		oc.Write(xlog.F{"foo": "bar"})
 		o.get()
 		oc.Close()
 		critialLogger = oldCritialLogger
@@ -86,12 +89,324 @@ func TestOutputChannelError(t *testing.T) {
 func TestOutputChannelClose(t *testing.T) {
 	oc := NewOutputChannel(newTestOutput())
 	defer oc.Close()
-	assert.NotNil(t, oc.stop)
 	oc.Close()
-	assert.Nil(t, oc.stop)
+	select {
+	case <-oc.done:
+	default:
+		t.Error("consumer not stopped")
+	}
 	oc.Close()
 }
 
+func TestOutputChannelStats(t *testing.T) {
+	oc := &OutputChannel{input: make(chan message, 2)}
+	assert.NoError(t, oc.Write(xlog.F{}))
+	assert.NoError(t, oc.Write(xlog.F{}))
+	assert.Equal(t, ErrBufferFull, oc.Write(xlog.F{}))
+	assert.Equal(t, OutputChannelStats{Queued: 2, Capacity: 2, Dropped: 1, DroppedLevels: map[string]uint64{"unknown": 1}}, oc.Stats())
+}
+
+// queuedIDs returns the id field of the messages in the buffer of oc.
+func queuedIDs(oc *OutputChannel) []interface{} {
+	ids := []interface{}{}
+	for len(oc.input) > 0 {
+		ids = append(ids, (<-oc.input).fields["id"])
+	}
+	return ids
+}
+
+func TestOutputChannelDropNewest(t *testing.T) {
+	oc := &OutputChannel{input: make(chan message, 2)}
+	for i := 1; i <= 2; i++ {
+		assert.NoError(t, oc.Write(xlog.F{"id": i}))
+	}
+	assert.Equal(t, ErrBufferFull, oc.Write(xlog.F{"id": 3}))
+	assert.Equal(t, ErrBufferFull, oc.Write(xlog.F{"id": 4}))
+	assert.Equal(t, uint64(2), oc.Stats().Dropped)
+	assert.Equal(t, []interface{}{1, 2}, queuedIDs(oc))
+}
+
+func TestOutputChannelDropOldest(t *testing.T) {
+	oc := &OutputChannel{input: make(chan message, 2), overflow: OverflowPolicy{Mode: DropOldest}}
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, oc.Write(xlog.F{"id": i}))
+	}
+	assert.Equal(t, uint64(3), oc.Stats().Dropped)
+	assert.Equal(t, []interface{}{4, 5}, queuedIDs(oc))
+
+	// Dropped events are released
+	l := New(Config{Output: oc, DisableCaller: true}).(*logger)
+	l.InfoEvent().Int("id", 1).Msg("test")
+	l.InfoEvent().Int("id", 2).Msg("test")
+	l.InfoEvent().Int("id", 3).Msg("test")
+	assert.Equal(t, uint64(4), oc.Stats().Dropped)
+	assert.Equal(t, int64(2), (<-oc.input).event.Fields()["id"])
+	assert.Equal(t, int64(3), (<-oc.input).event.Fields()["id"])
+
+	// Without buffer, there is nothing to evict
+	oc = &OutputChannel{input: make(chan message), overflow: OverflowPolicy{Mode: DropOldest}}
+	assert.Equal(t, ErrBufferFull, oc.Write(xlog.F{"id": 1}))
+	assert.Equal(t, uint64(1), oc.Stats().Dropped)
+}
+
+func TestOutputChannelBlockTimeout(t *testing.T) {
+	oc := &OutputChannel{input: make(chan message, 1), overflow: OverflowPolicy{Mode: Block, Timeout: 20 * time.Millisecond}}
+	assert.NoError(t, oc.Write(xlog.F{"id": 1}))
+	start := time.Now()
+	assert.Equal(t, ErrBufferFull, oc.Write(xlog.F{"id": 2}))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+	assert.Equal(t, uint64(1), oc.Stats().Dropped)
+
+	// The message is queued if room is made before the timeout
+	oc.overflow.Timeout = time.Second
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-oc.input
+	}()
+	assert.NoError(t, oc.Write(xlog.F{"id": 3}))
+	assert.Equal(t, uint64(1), oc.Stats().Dropped)
+	assert.Equal(t, []interface{}{3}, queuedIDs(oc))
+}
+
+// gateOutput blocks writes until its gate is closed.
+type gateOutput struct {
+	gate chan struct{}
+	o    *testOutput
+}
+
+func (o gateOutput) Write(fields map[string]interface{}) error {
+	<-o.gate
+	return o.o.Write(fields)
+}
+
+func TestOutputChannelBlock(t *testing.T) {
+	o := gateOutput{gate: make(chan struct{}), o: newTestOutput()}
+	oc := NewOutputChannelBuffer(o, 2, OverflowPolicy{Mode: Block})
+	defer oc.Close()
+	// The first message is held by the consumer and the two next fill the buffer
+	assert.NoError(t, oc.Write(xlog.F{"id": 1}))
+	for len(oc.input) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, oc.Write(xlog.F{"id": 2}))
+	assert.NoError(t, oc.Write(xlog.F{"id": 3}))
+	written := make(chan error)
+	go func() {
+		written <- oc.Write(xlog.F{"id": 4})
+	}()
+	select {
+	case <-written:
+		t.Fatal("write did not block")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(o.gate)
+	assert.NoError(t, <-written)
+	for i := 1; i <= 4; i++ {
+		assert.Equal(t, i, o.o.get()["id"])
+	}
+	assert.Equal(t, uint64(0), oc.Stats().Dropped)
+}
+
+func TestOutputChannelBlockClosed(t *testing.T) {
+	oc := &OutputChannel{input: make(chan message, 1), done: make(chan struct{}), overflow: OverflowPolicy{Mode: Block}}
+	assert.NoError(t, oc.Write(xlog.F{"id": 1}))
+	written := make(chan error)
+	go func() {
+		written <- oc.Write(xlog.F{"id": 2})
+	}()
+	close(oc.done)
+	assert.Equal(t, ErrBufferFull, <-written)
+	assert.Equal(t, uint64(1), oc.Stats().Dropped)
+	assert.Equal(t, []interface{}{1}, queuedIDs(oc))
+}
+
+func TestOutputChannelReserved(t *testing.T) {
+	o := newTestOutput()
+	oc := newOutputChannel(o, 2, []OverflowPolicy{{Reserved: 2}})
+	for i := 1; i <= 2; i++ {
+		assert.NoError(t, oc.Write(xlog.F{"level": "debug", "id": i}))
+	}
+	assert.Equal(t, ErrBufferFull, oc.Write(xlog.F{"level": "debug", "id": 3}))
+	assert.NoError(t, oc.Write(xlog.F{"level": "error", "id": 4}))
+	assert.NoError(t, oc.Write(xlog.F{"level": "warn", "id": 5}))
+	// Reserved buffer is full
+	assert.Equal(t, ErrBufferFull, oc.Write(xlog.F{"level": "error", "id": 6}))
+	assert.Equal(t, OutputChannelStats{Queued: 4, Capacity: 2, Reserved: 2, Dropped: 2, DroppedLevels: map[string]uint64{"debug": 1, "error": 1}}, oc.Stats())
+	oc.Flush()
+	for _, id := range []int{4, 5, 1, 2} {
+		assert.Equal(t, id, o.get()["id"])
+	}
+	assert.Equal(t, "dropped 2 messages: 1 debug, 1 error", o.get()["message"])
+}
+
+func TestOutputChannelReservedFallback(t *testing.T) {
+	oc := newOutputChannel(Discard, 2, []OverflowPolicy{{Reserved: 1}})
+	assert.NoError(t, oc.Write(xlog.F{"level": "error", "id": 1}))
+	assert.NoError(t, oc.Write(xlog.F{"level": "error", "id": 2}))
+	assert.Equal(t, 1, len(oc.priority))
+	assert.Equal(t, []interface{}{2}, queuedIDs(oc))
+}
+
+func TestOutputChannelReservedFirst(t *testing.T) {
+	o := gateOutput{gate: make(chan struct{}), o: newTestOutput()}
+	oc := NewOutputChannelBuffer(o, 10, OverflowPolicy{Reserved: 10})
+	defer oc.Close()
+	// The first message hangs in the output while the next are buffered
+	assert.NoError(t, oc.Write(xlog.F{"level": "debug", "id": 1}))
+	for len(oc.input) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, oc.Write(xlog.F{"level": "debug", "id": 2}))
+	assert.NoError(t, oc.Write(xlog.F{"level": "info", "id": 3}))
+	assert.NoError(t, oc.Write(xlog.F{"level": "error", "id": 4}))
+	close(o.gate)
+	for _, id := range []int{1, 4, 2, 3} {
+		assert.Equal(t, id, o.o.get()["id"])
+	}
+}
+
+func TestMessageUrgent(t *testing.T) {
+	keys := DefaultFieldKeys()
+	assert.True(t, message{event: &Event{level: xlog.LevelError}}.urgent(&keys))
+	assert.False(t, message{event: &Event{level: xlog.LevelInfo}}.urgent(&keys))
+	assert.True(t, message{level: xlog.LevelWarn, keys: &keys}.urgent(&keys))
+	assert.False(t, message{level: xlog.LevelDebug, keys: &keys}.urgent(&keys))
+	assert.True(t, message{level: levelNotice, keys: &keys}.urgent(&keys))
+	assert.True(t, message{fields: map[string]interface{}{"level": "fatal"}}.urgent(&keys))
+	assert.True(t, message{fields: map[string]interface{}{"level": "notice"}}.urgent(&keys))
+	assert.False(t, message{fields: map[string]interface{}{"level": "trace"}}.urgent(&keys))
+	assert.False(t, message{fields: map[string]interface{}{"level": "foo"}}.urgent(&keys))
+	assert.False(t, message{fields: map[string]interface{}{}}.urgent(&keys))
+}
+
+func TestOutputChannelShutdown(t *testing.T) {
+	o := gateOutput{gate: make(chan struct{}), o: newTestOutput()}
+	oc := NewOutputChannelBuffer(o, 10)
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, oc.Write(xlog.F{"id": i}))
+	}
+	close(o.gate)
+	assert.NoError(t, oc.Shutdown(context.Background()))
+	for i := 1; i <= 5; i++ {
+		assert.Equal(t, i, o.o.get()["id"])
+	}
+	assert.Equal(t, ErrOutputClosed, oc.Write(xlog.F{"id": 6}))
+	assert.Equal(t, uint64(1), oc.Stats().Dropped)
+	assert.NoError(t, oc.Shutdown(context.Background()))
+	assert.True(t, o.o.empty())
+}
+
+func TestOutputChannelShutdownDeadline(t *testing.T) {
+	o := gateOutput{gate: make(chan struct{}), o: newTestOutput()}
+	oc := NewOutputChannelBuffer(o, 10)
+	// The first message hangs in the output while the two next are buffered
+	assert.NoError(t, oc.Write(xlog.F{"id": 1}))
+	for len(oc.input) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, oc.Write(xlog.F{"id": 2}))
+	assert.NoError(t, oc.Write(xlog.F{"id": 3}))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := oc.Shutdown(ctx)
+	if se, ok := err.(*ShutdownError); assert.True(t, ok, "%v", err) {
+		assert.Equal(t, 3, se.Lost)
+		assert.Equal(t, context.DeadlineExceeded, se.Unwrap())
+		assert.Equal(t, "output channel shutdown: 3 messages lost: context deadline exceeded", err.Error())
+	}
+	assert.Equal(t, uint64(2), oc.Stats().Dropped)
+
+	// The consumer exits once the output returns
+	close(o.gate)
+	assert.Equal(t, 1, o.o.get()["id"])
+	assert.NoError(t, oc.Shutdown(context.Background()))
+	assert.True(t, o.o.empty())
+}
+
+func TestOutputChannelShutdownBlocked(t *testing.T) {
+	o := gateOutput{gate: make(chan struct{}), o: newTestOutput()}
+	oc := NewOutputChannelBuffer(o, 1, OverflowPolicy{Mode: Block})
+	// The first message hangs in the output, the second is buffered and the
+	// third waits for room
+	assert.NoError(t, oc.Write(xlog.F{"id": 1}))
+	for len(oc.input) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, oc.Write(xlog.F{"id": 2}))
+	written := make(chan error)
+	go func() {
+		written <- oc.Write(xlog.F{"id": 3})
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := oc.Shutdown(ctx)
+	assert.Equal(t, ErrOutputClosed, <-written)
+	if se, ok := err.(*ShutdownError); assert.True(t, ok, "%v", err) {
+		assert.Equal(t, 2, se.Lost)
+	}
+	assert.Equal(t, uint64(2), oc.Stats().Dropped)
+	close(o.gate)
+}
+
+// countOutput counts the written messages, except the drop summaries.
+type countOutput struct {
+	n uint64
+}
+
+func (o *countOutput) Write(fields map[string]interface{}) error {
+	if _, ok := fields["message"]; !ok {
+		atomic.AddUint64(&o.n, 1)
+	}
+	return nil
+}
+
+func TestOutputChannelShutdownWriters(t *testing.T) {
+	for _, mode := range []OverflowMode{DropNewest, DropOldest, Block} {
+		o := &countOutput{}
+		oc := NewOutputChannelBuffer(o, 10, OverflowPolicy{Mode: mode})
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					oc.Write(xlog.F{})
+				}
+			}()
+		}
+		time.Sleep(time.Millisecond)
+		assert.NoError(t, oc.Shutdown(context.Background()))
+		wg.Wait()
+		// No message is lost without being counted
+		assert.Equal(t, uint64(1600), atomic.LoadUint64(&o.n)+oc.Stats().Dropped, "mode %d", mode)
+	}
+}
+
+func TestOutputChannelShutdownConcurrent(t *testing.T) {
+	o := newTestOutput()
+	oc := NewOutputChannel(o)
+	oc.Write(xlog.F{"id": 1})
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			errs <- oc.Shutdown(context.Background())
+		}()
+	}
+	oc.Close()
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, <-errs)
+	}
+	assert.Equal(t, 1, o.get()["id"])
+}
+
+func TestOutputChannelShutdownNoConsumer(t *testing.T) {
+	o := newTestOutput()
+	oc := &OutputChannel{input: make(chan message, 1), output: o}
+	oc.Write(xlog.F{"id": 1})
+	assert.NoError(t, oc.Shutdown(context.Background()))
+	assert.Equal(t, 1, o.get()["id"])
+}
+
 func TestDiscard(t *testing.T) {
 	assert.NoError(t, Discard.Write(xlog.F{}))
 }
@@ -184,6 +499,27 @@ func TestLevelOutput(t *testing.T) {
 	assert.True(t, oWarn.empty())
 }
 
+func TestLevelOutputExtraLevels(t *testing.T) {
+	oTrace := newTestOutput()
+	oWarn := newTestOutput()
+	oPanic := newTestOutput()
+	l := LevelOutput{
+		Trace: oTrace,
+		Warn:  oWarn,
+		Panic: oPanic,
+	}
+	assert.NoError(t, l.Write(xlog.F{"level": "trace"}))
+	assert.Equal(t, xlog.F{"level": "trace"}, xlog.F(<-oTrace.w))
+	assert.NoError(t, l.Write(xlog.F{"level": "panic"}))
+	assert.Equal(t, xlog.F{"level": "panic"}, xlog.F(<-oPanic.w))
+	assert.NoError(t, l.Write(xlog.F{"level": "notice"}))
+	assert.Equal(t, xlog.F{"level": "notice"}, xlog.F(<-oWarn.w))
+	assert.NoError(t, l.Write(xlog.F{"level": "foo"}))
+	assert.True(t, oTrace.empty())
+	assert.True(t, oWarn.empty())
+	assert.True(t, oPanic.empty())
+}
+
 func TestSyslogOutput(t *testing.T) {
 	buf := bytes.NewBuffer(nil)
 	critialLoggerMux.Lock()
@@ -240,7 +576,7 @@ func TestNewConsoleOutputW(t *testing.T) {
 
 func TestConsoleOutput(t *testing.T) {
 	buf := &bytes.Buffer{}
-	c := consoleOutput{w: buf}
+	c := consoleOutput{w: buf, keys: DefaultFieldKeys()}
 	err := c.Write(xlog.F{"message": "some message", "level": "info", "time": time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC), "foo": "bar"})
 	assert.NoError(t, err)
 	assert.Equal(t, "2000/01/02 03:04:05 \x1b[34mINFO\x1b[0m some message \x1b[32mfoo\x1b[0m=bar\n", buf.String())
@@ -258,6 +594,22 @@ func TestConsoleOutput(t *testing.T) {
 	assert.Equal(t, "\x1b[31mERRO\x1b[0m some error\n", buf.String())
 }
 
+func TestConsoleOutputExtraLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := consoleOutput{w: buf, keys: DefaultFieldKeys()}
+	assert.NoError(t, c.Write(xlog.F{"message": "test", "level": "trace"}))
+	assert.Equal(t, "\x1b[37mTRAC\x1b[0m test\n", buf.String())
+	buf.Reset()
+	assert.NoError(t, c.Write(xlog.F{"message": "test", "level": "panic"}))
+	assert.Equal(t, "\x1b[31mPANI\x1b[0m test\n", buf.String())
+	buf.Reset()
+	assert.NoError(t, c.Write(xlog.F{"message": "test", "level": "notice"}))
+	assert.Equal(t, "\x1b[36mNOTI\x1b[0m test\n", buf.String())
+	buf.Reset()
+	assert.NoError(t, c.Write(xlog.F{"message": "test", "level": "ok"}))
+	assert.Equal(t, "\x1b[34mOK  \x1b[0m test\n", buf.String())
+}
+
 func TestLogfmtOutput(t *testing.T) {
 	buf := &bytes.Buffer{}
 	c := NewLogfmtOutput(buf)
@@ -297,6 +649,13 @@ func TestLogstashOutput(t *testing.T) {
 	assert.Equal(t, "{\"@timestamp\":\"2000-01-02T03:04:05Z\",\"@version\":1,\"file\":\"test.go:234\",\"foo\":\"bar\",\"level\":\"INFO\",\"message\":\"some message\"}", buf.String())
 }
 
+func TestLogstashOutputCustomLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o := NewLogstashOutput(buf)
+	assert.NoError(t, o.Write(xlog.F{"level": "notice"}))
+	assert.Equal(t, "{\"@version\":1,\"level\":\"NOTICE\"}", buf.String())
+}
+
 func TestUIDOutput(t *testing.T) {
 	o := newTestOutput()
 	i := NewUIDOutput("id", o)
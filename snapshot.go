@@ -0,0 +1,170 @@
+package xlog
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/rs/xlog"
+)
+
+// maxSnapshotDepth limits the depth of the copied values so long linked
+// structures don't exhaust the stack. Values deeper than this are kept by
+// reference.
+const maxSnapshotDepth = 16
+
+// snapshotValue returns a deep copy of v when v contains maps, slices, arrays or
+// pointers so the caller can modify them once the log call returned. Unexported
+// struct fields are copied by value only. Errors are kept as is. Maps, slices
+// and pointers referenced several times, like in cyclic structures, are copied
+// once and the copy keeps sharing them.
+func snapshotValue(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16,
+		uint32, uint64, float32, float64, time.Time, time.Duration, error:
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	if !isComposite(rv.Kind()) {
+		return v
+	}
+	c := &copier{}
+	return c.copyValue(rv, 0).Interface()
+}
+
+// isComposite returns true if values of kind k may reference mutable memory.
+func isComposite(k reflect.Kind) bool {
+	switch k {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Ptr, reflect.Struct, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// visitKey identifies a map, slice or pointer.
+type visitKey struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// copier deep copies values, keeping the copies of the maps, slices and
+// pointers already seen.
+type copier struct {
+	seen map[visitKey]reflect.Value
+}
+
+// visited returns the copy of the value identified by k if already seen.
+func (c *copier) visited(k visitKey) (reflect.Value, bool) {
+	v, ok := c.seen[k]
+	return v, ok
+}
+
+// visit records cp as the copy of the value identified by k.
+func (c *copier) visit(k visitKey, cp reflect.Value) {
+	if c.seen == nil {
+		c.seen = map[visitKey]reflect.Value{}
+	}
+	c.seen[k] = cp
+}
+
+// copyValue returns a deep copy of v or v itself if it does not need a copy.
+func (c *copier) copyValue(v reflect.Value, depth int) reflect.Value {
+	if depth > maxSnapshotDepth {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		k := visitKey{ptr: v.Pointer(), typ: v.Type()}
+		if cp, ok := c.visited(k); ok {
+			return cp
+		}
+		cp := reflect.MakeMap(v.Type())
+		c.visit(k, cp)
+		for _, key := range v.MapKeys() {
+			cp.SetMapIndex(key, c.copyValue(v.MapIndex(key), depth+1))
+		}
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		k := visitKey{ptr: v.Pointer(), len: v.Len(), typ: v.Type()}
+		if cp, ok := c.visited(k); ok {
+			return cp
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		c.visit(k, cp)
+		c.copyElems(cp, v, depth)
+		return cp
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		c.copyElems(cp, v, depth)
+		return cp
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		k := visitKey{ptr: v.Pointer(), typ: v.Type()}
+		if cp, ok := c.visited(k); ok {
+			return cp
+		}
+		cp := reflect.New(v.Type().Elem())
+		c.visit(k, cp)
+		cp.Elem().Set(c.copyValue(v.Elem(), depth+1))
+		return cp
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		for i := 0; i < cp.NumField(); i++ {
+			if f := cp.Field(i); f.CanSet() {
+				f.Set(c.copyValue(v.Field(i), depth+1))
+			}
+		}
+		return cp
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(c.copyValue(v.Elem(), depth+1))
+		return cp
+	}
+	return v
+}
+
+// copyElems copies the elements of the slice or array src to dst.
+func (c *copier) copyElems(dst, src reflect.Value, depth int) {
+	if !isComposite(src.Type().Elem().Kind()) {
+		reflect.Copy(dst, src)
+		return
+	}
+	for i := 0; i < src.Len(); i++ {
+		dst.Index(i).Set(c.copyValue(src.Index(i), depth+1))
+	}
+}
+
+// snapshotFields replaces the values of fields by a deep copy.
+func snapshotFields(fields map[string]interface{}) {
+	for k, v := range fields {
+		fields[k] = snapshotValue(v)
+	}
+}
+
+// snapshot replaces the values of the event and logger fields by a deep copy.
+func (e *Event) snapshot() {
+	for i := range e.fields {
+		if e.fields[i].Type == InterfaceType {
+			e.fields[i].Interface = snapshotValue(e.fields[i].Interface)
+		}
+	}
+	if len(e.ctx) > 0 {
+		ctx := make(xlog.F, len(e.ctx))
+		for k, v := range e.ctx {
+			ctx[k] = snapshotValue(v)
+		}
+		e.ctx = ctx
+	}
+}
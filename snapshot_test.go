@@ -0,0 +1,129 @@
+package xlog
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type snapshotStruct struct {
+	Name   string
+	Tags   []string
+	Attrs  map[string]int
+	Next   *snapshotStruct
+	hidden []int
+}
+
+func TestSnapshotValue(t *testing.T) {
+	err := errors.New("err")
+	for _, v := range []interface{}{nil, "foo", 1, 1.5, true, time.Second, err} {
+		assert.Equal(t, v, snapshotValue(v))
+	}
+
+	m := map[string]interface{}{"slice": []int{1, 2}, "map": map[string]string{"a": "b"}}
+	c := snapshotValue(m).(map[string]interface{})
+	m["new"] = 1
+	m["slice"].([]int)[0] = 42
+	m["map"].(map[string]string)["a"] = "changed"
+	assert.Equal(t, map[string]interface{}{"slice": []int{1, 2}, "map": map[string]string{"a": "b"}}, c)
+
+	hidden := []int{1}
+	s := &snapshotStruct{Name: "a", Tags: []string{"x"}, Attrs: map[string]int{"n": 1}, Next: &snapshotStruct{Name: "b"}, hidden: hidden}
+	cs := snapshotValue(s).(*snapshotStruct)
+	s.Name = "changed"
+	s.Tags[0] = "changed"
+	s.Attrs["n"] = 2
+	s.Next.Name = "changed"
+	assert.Equal(t, "a", cs.Name)
+	assert.Equal(t, []string{"x"}, cs.Tags)
+	assert.Equal(t, map[string]int{"n": 1}, cs.Attrs)
+	assert.Equal(t, "b", cs.Next.Name)
+	// Unexported fields are copied by value only
+	hidden[0] = 2
+	assert.Equal(t, []int{2}, cs.hidden)
+
+	a := [2][]int{{1}, {2}}
+	ca := snapshotValue(a).([2][]int)
+	a[0][0] = 42
+	assert.Equal(t, [2][]int{{1}, {2}}, ca)
+
+	var nilMap map[string]int
+	assert.Nil(t, snapshotValue(nilMap))
+}
+
+func TestSnapshotValueCycle(t *testing.T) {
+	s := &snapshotStruct{Name: "a"}
+	s.Next = s
+	c := snapshotValue(s).(*snapshotStruct)
+	assert.Equal(t, "a", c.Next.Name)
+	assert.True(t, c.Next == c)
+	assert.False(t, c == s)
+}
+
+type snapshotNode struct {
+	Name       string
+	Prev, Next *snapshotNode
+	Self       *snapshotNode
+}
+
+func TestSnapshotValueShared(t *testing.T) {
+	// Doubly linked list with self pointers
+	var head, prev *snapshotNode
+	for i := 0; i < 20; i++ {
+		n := &snapshotNode{Name: string(rune('a' + i)), Prev: prev}
+		n.Self = n
+		if prev == nil {
+			head = n
+		} else {
+			prev.Next = n
+		}
+		prev = n
+	}
+	c := snapshotValue(head).(*snapshotNode)
+	assert.True(t, c.Self == c)
+	assert.True(t, c.Next.Prev == c)
+	assert.True(t, c.Next.Self == c.Next)
+	head.Next.Name = "changed"
+	assert.Equal(t, "b", c.Next.Name)
+
+	// Shared references stay shared
+	tags := []string{"a"}
+	attrs := map[string]int{"n": 1}
+	m := map[string]interface{}{"t1": tags, "t2": tags, "a1": attrs, "a2": attrs}
+	cm := snapshotValue(m).(map[string]interface{})
+	cm["t1"].([]string)[0] = "b"
+	assert.Equal(t, []string{"b"}, cm["t2"])
+	cm["a1"].(map[string]int)["n"] = 2
+	assert.Equal(t, map[string]int{"n": 2}, cm["a2"])
+	assert.Equal(t, []string{"a"}, tags)
+}
+
+func TestSnapshotFields(t *testing.T) {
+	o := &testOutput{w: make(chan map[string]interface{}, 200)}
+	oc := NewOutputChannelBuffer(o, 200)
+	defer oc.Close()
+	tags := []string{"a"}
+	l := New(Config{Output: oc, SnapshotFields: true, Fields: xlog.F{"ctx": map[string]int{"n": 1}}})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			l.Info("test", xlog.F{"tags": tags})
+			l.InfoEvent().Interface("tags", tags).Msg("test")
+			tags[0] = "b"
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		last := o.get()
+		if assert.Len(t, last["tags"], 1) {
+			assert.Contains(t, []string{"a", "b"}, last["tags"].([]string)[0])
+		}
+		assert.Equal(t, map[string]int{"n": 1}, last["ctx"])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,135 @@
+package xlog
+
+import (
+	"bytes"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/xlog"
+)
+
+// maxStackDepth is the maximum number of frames captured in a stack trace.
+const maxStackDepth = 64
+
+// stackEnabled returns true if a stack trace must be added to messages of the
+// given level.
+func (l *logger) stackEnabled(level xlog.Level) bool {
+	return l.stackTrace && severity(level) >= severity(l.stackTraceLevel)
+}
+
+// captureStack returns the formatted stack trace of the caller. The calldepth
+// argument is the number of stack frames to skip as for runtime.Caller.
+func captureStack(calldepth int) string {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(calldepth+2, pcs[:])
+	return formatStack(pcs[:n])
+}
+
+// formatStack formats program counters the same way as a panic stack trace:
+// the function name followed by the file and line on a second line indented
+// with a tab.
+func formatStack(pcs []uintptr) string {
+	buf := &bytes.Buffer{}
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(f.Function)
+		buf.WriteString("\n\t")
+		buf.WriteString(f.File)
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(f.Line))
+		if !more {
+			break
+		}
+	}
+	return buf.String()
+}
+
+// errorStack returns the formatted stack trace carried by err or by one of the
+// errors it wraps, including the ones joined by errors.Join. The innermost stack
+// is returned as it is the closest to the origin of the error, the one of the
+// last joined error carrying a stack when several are. Errors carrying a stack
+// are recognized by a StackTrace method returning a slice of program counters,
+// like the errors created by github.com/pkg/errors.
+func errorStack(err error) string {
+	var pcs []uintptr
+	walkErrors(err, func(err error) {
+		if s := stackTrace(err); s != nil {
+			pcs = s
+		}
+	})
+	if pcs == nil {
+		return ""
+	}
+	return formatStack(pcs)
+}
+
+// stackTrace calls the StackTrace method of err if it exists and returns a
+// slice of uintptr. Reflection is used so packages like github.com/pkg/errors,
+// which return their own named types, are supported without depending on them.
+func stackTrace(err error) []uintptr {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() {
+		return nil
+	}
+	t := m.Type()
+	if t.NumIn() != 0 || t.NumOut() != 1 || t.Out(0).Kind() != reflect.Slice || t.Out(0).Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+	s := m.Call(nil)[0]
+	pcs := make([]uintptr, s.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(s.Index(i).Uint())
+	}
+	return pcs
+}
+
+// fieldsStack returns the stack trace carried by the first error found in
+// fields, sorted by key, or an empty string.
+func fieldsStack(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for k, v := range fields {
+		if _, ok := v.(error); ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err, ok := fields[k].(error); ok {
+			if s := errorStack(err); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// stackValue returns the value of a stack field and true if it must be rendered
+// on its own lines by human oriented outputs.
+func stackValue(v interface{}) (string, bool) {
+	s, ok := v.(string)
+	return s, ok && strings.IndexByte(s, '\n') != -1
+}
+
+// stackField is the same as stackValue for a typed field.
+func stackField(f Field) (string, bool) {
+	if f.Type != StringType {
+		return stackValue(f.Interface)
+	}
+	return stackValue(f.String)
+}
+
+// writeStack writes each line of the stack indented with a tab.
+func writeStack(buf *bytes.Buffer, stack string) {
+	for _, line := range strings.Split(stack, "\n") {
+		buf.WriteByte('\t')
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+}
@@ -0,0 +1,149 @@
+package xlog
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stackError mimics the errors of github.com/pkg/errors
+type stackError struct {
+	msg   string
+	stack []uintptr
+}
+
+type frame uintptr
+
+type stackTraceFrames []frame
+
+func newStackError(msg string) error {
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:])
+	return &stackError{msg: msg, stack: pcs[:n]}
+}
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) StackTrace() stackTraceFrames {
+	f := make(stackTraceFrames, len(e.stack))
+	for i, pc := range e.stack {
+		f[i] = frame(pc)
+	}
+	return f
+}
+
+func stackErrorOrigin() error {
+	return newStackError("some error")
+}
+
+func TestStackTrace(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, StackTrace: true})
+	l.Warn("test")
+	_, found := o.get()["stack"]
+	assert.False(t, found)
+
+	l.Error("test")
+	stack, _ := o.get()["stack"].(string)
+	assert.True(t, strings.HasPrefix(stack, "github.com/kanmu/xlog.TestStackTrace\n\t"), stack)
+	assert.Contains(t, stack, "stack_test.go:")
+
+	l.ErrorEvent().Msg("test")
+	stack, _ = o.get()["stack"].(string)
+	assert.True(t, strings.HasPrefix(stack, "github.com/kanmu/xlog.TestStackTrace\n\t"), stack)
+
+	l.With(xlog.F{"foo": "bar"}).Errorf("test %d", 1)
+	stack, _ = o.get()["stack"].(string)
+	assert.True(t, strings.HasPrefix(stack, "github.com/kanmu/xlog.TestStackTrace\n\t"), stack)
+}
+
+func TestStackTraceLevel(t *testing.T) {
+	o := newTestOutput()
+	level := xlog.LevelWarn
+	l := New(Config{Output: o, StackTrace: true, StackTraceLevel: &level})
+	l.Info("test")
+	assert.NotContains(t, o.get(), "stack")
+	l.Warn("test")
+	assert.Contains(t, o.get(), "stack")
+
+	// Messages below xlog.LevelError get no stack trace by default
+	l = New(Config{Output: o, StackTrace: true})
+	l.Debug("test")
+	assert.NotContains(t, o.get(), "stack")
+	l.Warn("test")
+	assert.NotContains(t, o.get(), "stack")
+	l.Error("test")
+	assert.Contains(t, o.get(), "stack")
+}
+
+func TestStackTraceFromError(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, StackTrace: true})
+	err := wrap("wrapped", stackErrorOrigin())
+	l.Error("test", xlog.F{"error": err})
+	stack, _ := o.get()["stack"].(string)
+	assert.True(t, strings.HasPrefix(stack, "github.com/kanmu/xlog.stackErrorOrigin\n\t"), stack)
+
+	l.ErrorEvent().Err(err).Msg("test")
+	stack, _ = o.get()["stack"].(string)
+	assert.True(t, strings.HasPrefix(stack, "github.com/kanmu/xlog.stackErrorOrigin\n\t"), stack)
+
+	assert.Equal(t, "", errorStack(errors.New("no stack")))
+}
+
+func stackErrorOther() error {
+	return newStackError("other error")
+}
+
+func TestStackTraceFromJoinedError(t *testing.T) {
+	err := join(errors.New("no stack"), wrap("wrapped", stackErrorOrigin()))
+	stack := errorStack(err)
+	assert.True(t, strings.HasPrefix(stack, "github.com/kanmu/xlog.stackErrorOrigin\n\t"), stack)
+}
+
+func TestStackTraceFromFieldsSorted(t *testing.T) {
+	fields := map[string]interface{}{"b": stackErrorOther(), "a": stackErrorOrigin(), "c": "foo"}
+	for i := 0; i < 20; i++ {
+		stack := fieldsStack(fields)
+		assert.True(t, strings.HasPrefix(stack, "github.com/kanmu/xlog.stackErrorOrigin\n\t"), stack)
+	}
+}
+
+func TestStackTraceOutput(t *testing.T) {
+	fields := map[string]interface{}{
+		"time":    time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC),
+		"level":   "error",
+		"message": "test",
+		"foo":     "bar",
+		"stack":   "main.foo\n\t/path/file.go:12\nmain.main\n\t/path/main.go:5",
+	}
+	stack := "\tmain.foo\n\t\t/path/file.go:12\n\tmain.main\n\t\t/path/main.go:5\n"
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, NewLogfmtOutput(buf).Write(fields))
+	assert.Equal(t, "level=error message=test time=\"2000-01-02 03:04:05 +0000 UTC\" foo=bar\n"+stack, buf.String())
+
+	buf.Reset()
+	e := &Event{level: xlog.LevelError, msg: "test", time: fields["time"].(time.Time), fields: []Field{
+		{Key: "foo", Type: StringType, String: "bar"},
+		{Key: "stack", Type: StringType, String: fields["stack"].(string)},
+	}}
+	assert.NoError(t, NewLogfmtOutput(buf).(EventOutput).WriteEvent(e))
+	assert.Equal(t, "level=error message=test time=\"2000-01-02 03:04:05 +0000 UTC\" foo=bar\n"+stack, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, consoleOutput{w: buf, keys: DefaultFieldKeys()}.Write(fields))
+	assert.Equal(t, "2000/01/02 03:04:05 \x1b[31mERRO\x1b[0m test \x1b[32mfoo\x1b[0m=bar\n"+stack, buf.String())
+
+	// Single line stack values are written as regular fields
+	buf.Reset()
+	fields["stack"] = "none"
+	assert.NoError(t, NewLogfmtOutput(buf).Write(fields))
+	assert.Equal(t, "level=error message=test time=\"2000-01-02 03:04:05 +0000 UTC\" foo=bar stack=none\n", buf.String())
+}
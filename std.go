@@ -1,6 +1,7 @@
 package xlog
 
 import (
+	"context"
 	"fmt"
 	"github.com/rs/xlog"
 )
@@ -14,44 +15,83 @@ func SetLogger(logger Logger) {
 	std = logger
 }
 
+// Trace calls the Trace() method on the default logger
+func Trace(v ...interface{}) {
+	if !std.Enabled(LevelTrace) {
+		return
+	}
+	f := extractFields(&v)
+	std.OutputF(LevelTrace, 2, fmt.Sprint(v...), f)
+}
+
+// Tracef calls the Tracef() method on the default logger
+func Tracef(format string, v ...interface{}) {
+	if !std.Enabled(LevelTrace) {
+		return
+	}
+	f := extractFields(&v)
+	std.OutputF(LevelTrace, 2, fmt.Sprintf(format, v...), f)
+}
+
 // Debug calls the Debug() method on the default logger
 func Debug(v ...interface{}) {
+	if !std.Enabled(xlog.LevelDebug) {
+		return
+	}
 	f := extractFields(&v)
 	std.OutputF(xlog.LevelDebug, 2, fmt.Sprint(v...), f)
 }
 
 // Debugf calls the Debugf() method on the default logger
 func Debugf(format string, v ...interface{}) {
+	if !std.Enabled(xlog.LevelDebug) {
+		return
+	}
 	f := extractFields(&v)
 	std.OutputF(xlog.LevelDebug, 2, fmt.Sprintf(format, v...), f)
 }
 
 // Info calls the Info() method on the default logger
 func Info(v ...interface{}) {
+	if !std.Enabled(xlog.LevelInfo) {
+		return
+	}
 	f := extractFields(&v)
 	std.OutputF(xlog.LevelInfo, 2, fmt.Sprint(v...), f)
 }
 
 // Infof calls the Infof() method on the default logger
 func Infof(format string, v ...interface{}) {
+	if !std.Enabled(xlog.LevelInfo) {
+		return
+	}
 	f := extractFields(&v)
 	std.OutputF(xlog.LevelInfo, 2, fmt.Sprintf(format, v...), f)
 }
 
 // Warn calls the Warn() method on the default logger
 func Warn(v ...interface{}) {
+	if !std.Enabled(xlog.LevelWarn) {
+		return
+	}
 	f := extractFields(&v)
 	std.OutputF(xlog.LevelWarn, 2, fmt.Sprint(v...), f)
 }
 
 // Warnf calls the Warnf() method on the default logger
 func Warnf(format string, v ...interface{}) {
+	if !std.Enabled(xlog.LevelWarn) {
+		return
+	}
 	f := extractFields(&v)
 	std.OutputF(xlog.LevelWarn, 2, fmt.Sprintf(format, v...), f)
 }
 
 // Error calls the Error() method on the default logger
 func Error(v ...interface{}) {
+	if !std.Enabled(xlog.LevelError) {
+		return
+	}
 	f := extractFields(&v)
 	std.OutputF(xlog.LevelError, 2, fmt.Sprint(v...), f)
 }
@@ -61,6 +101,9 @@ func Error(v ...interface{}) {
 // Go vet users: you may append %v at the end of you format when using xlog.F{} as a last
 // argument to workaround go vet false alarm.
 func Errorf(format string, v ...interface{}) {
+	if !std.Enabled(xlog.LevelError) {
+		return
+	}
 	f := extractFields(&v)
 	if f != nil {
 		// Let user add a %v at the end of the message when fields are passed to satisfy go vet
@@ -74,8 +117,10 @@ func Errorf(format string, v ...interface{}) {
 
 // Fatal calls the Fatal() method on the default logger
 func Fatal(v ...interface{}) {
-	f := extractFields(&v)
-	std.OutputF(xlog.LevelFatal, 2, fmt.Sprint(v...), f)
+	if std.Enabled(xlog.LevelFatal) {
+		f := extractFields(&v)
+		std.OutputF(xlog.LevelFatal, 2, fmt.Sprint(v...), f)
+	}
 	if l, ok := std.(*logger); ok {
 		if o, ok := l.output.(*OutputChannel); ok {
 			o.Close()
@@ -89,6 +134,43 @@ func Fatal(v ...interface{}) {
 // Go vet users: you may append %v at the end of you format when using xlog.F{} as a last
 // argument to workaround go vet false alarm.
 func Fatalf(format string, v ...interface{}) {
+	if std.Enabled(xlog.LevelFatal) {
+		f := extractFields(&v)
+		if f != nil {
+			// Let user add a %v at the end of the message when fields are passed to satisfy go vet
+			l := len(format)
+			if l > 2 && format[l-2] == '%' && format[l-1] == 'v' {
+				format = format[0 : l-2]
+			}
+		}
+		std.OutputF(xlog.LevelFatal, 2, fmt.Sprintf(format, v...), f)
+	}
+	if l, ok := std.(*logger); ok {
+		if o, ok := l.output.(*OutputChannel); ok {
+			o.Close()
+		}
+	}
+	exit1()
+}
+
+// Panic calls the Panic() method on the default logger
+func Panic(v ...interface{}) {
+	f := extractFields(&v)
+	msg := fmt.Sprint(v...)
+	std.OutputF(LevelPanic, 2, msg, f)
+	if l, ok := std.(*logger); ok {
+		if o, ok := l.output.(*OutputChannel); ok {
+			o.Flush()
+		}
+	}
+	panic(msg)
+}
+
+// Panicf calls the Panicf() method on the default logger
+//
+// Go vet users: you may append %v at the end of you format when using xlog.F{} as a last
+// argument to workaround go vet false alarm.
+func Panicf(format string, v ...interface{}) {
 	f := extractFields(&v)
 	if f != nil {
 		// Let user add a %v at the end of the message when fields are passed to satisfy go vet
@@ -97,7 +179,77 @@ func Fatalf(format string, v ...interface{}) {
 			format = format[0 : l-2]
 		}
 	}
-	std.OutputF(xlog.LevelFatal, 2, fmt.Sprintf(format, v...), f)
+	msg := fmt.Sprintf(format, v...)
+	std.OutputF(LevelPanic, 2, msg, f)
+	if l, ok := std.(*logger); ok {
+		if o, ok := l.output.(*OutputChannel); ok {
+			o.Flush()
+		}
+	}
+	panic(msg)
+}
+
+// outputCtx sends a message with the fields extracted from ctx on the default
+// logger.
+func outputCtx(ctx context.Context, level xlog.Level, msg string, f map[string]interface{}) {
+	if l, ok := std.(*logger); ok {
+		l.sendCtx(ctx, level, 3, msg, f)
+		return
+	}
+	std.OutputF(level, 3, msg, mergeFields(extractContext(ctx, defaultKeys(), nil), nil, f))
+}
+
+// TraceCtx calls the TraceCtx() method on the default logger
+func TraceCtx(ctx context.Context, v ...interface{}) {
+	if !std.Enabled(LevelTrace) {
+		return
+	}
+	f := extractFields(&v)
+	outputCtx(ctx, LevelTrace, fmt.Sprint(v...), f)
+}
+
+// DebugCtx calls the DebugCtx() method on the default logger
+func DebugCtx(ctx context.Context, v ...interface{}) {
+	if !std.Enabled(xlog.LevelDebug) {
+		return
+	}
+	f := extractFields(&v)
+	outputCtx(ctx, xlog.LevelDebug, fmt.Sprint(v...), f)
+}
+
+// InfoCtx calls the InfoCtx() method on the default logger
+func InfoCtx(ctx context.Context, v ...interface{}) {
+	if !std.Enabled(xlog.LevelInfo) {
+		return
+	}
+	f := extractFields(&v)
+	outputCtx(ctx, xlog.LevelInfo, fmt.Sprint(v...), f)
+}
+
+// WarnCtx calls the WarnCtx() method on the default logger
+func WarnCtx(ctx context.Context, v ...interface{}) {
+	if !std.Enabled(xlog.LevelWarn) {
+		return
+	}
+	f := extractFields(&v)
+	outputCtx(ctx, xlog.LevelWarn, fmt.Sprint(v...), f)
+}
+
+// ErrorCtx calls the ErrorCtx() method on the default logger
+func ErrorCtx(ctx context.Context, v ...interface{}) {
+	if !std.Enabled(xlog.LevelError) {
+		return
+	}
+	f := extractFields(&v)
+	outputCtx(ctx, xlog.LevelError, fmt.Sprint(v...), f)
+}
+
+// FatalCtx calls the FatalCtx() method on the default logger
+func FatalCtx(ctx context.Context, v ...interface{}) {
+	if std.Enabled(xlog.LevelFatal) {
+		f := extractFields(&v)
+		outputCtx(ctx, xlog.LevelFatal, fmt.Sprint(v...), f)
+	}
 	if l, ok := std.(*logger); ok {
 		if o, ok := l.output.(*OutputChannel); ok {
 			o.Close()
@@ -105,3 +257,16 @@ func Fatalf(format string, v ...interface{}) {
 	}
 	exit1()
 }
+
+// PanicCtx calls the PanicCtx() method on the default logger
+func PanicCtx(ctx context.Context, v ...interface{}) {
+	f := extractFields(&v)
+	msg := fmt.Sprint(v...)
+	outputCtx(ctx, LevelPanic, msg, f)
+	if l, ok := std.(*logger); ok {
+		if o, ok := l.output.(*OutputChannel); ok {
+			o.Flush()
+		}
+	}
+	panic(msg)
+}
@@ -1,6 +1,7 @@
 package xlog
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -130,3 +131,72 @@ func TestStdFatalf(t *testing.T) {
 	assert.Equal(t, map[string]interface{}{"time": fakeNow, "level": "fatal", "message": "test 1", "foo": "bar"}, last)
 	assert.Equal(t, 1, exited)
 }
+
+func TestStdTrace(t *testing.T) {
+	o := newTestOutput()
+	oldStd := std
+	defer func() { std = oldStd }()
+	SetLogger(New(Config{Level: LevelTrace, Output: o}))
+	Trace("test", xlog.F{"foo": "bar"})
+	last := <-o.w
+	assert.Equal(t, "trace", last["level"])
+	assert.Equal(t, "bar", last["foo"])
+	Tracef("test %d", 1)
+	assert.Equal(t, "test 1", (<-o.w)["message"])
+}
+
+func TestStdPanic(t *testing.T) {
+	o := newTestOutput()
+	oldStd := std
+	defer func() { std = oldStd }()
+	SetLogger(New(Config{Output: NewOutputChannel(o), NowGetter: func() time.Time { return fakeNow }}))
+	assert.PanicsWithValue(t, "test", func() { Panic("test", xlog.F{"foo": "bar"}) })
+	last := <-o.w
+	assert.Contains(t, last["file"], "std_test.go:")
+	delete(last, "file")
+	assert.Equal(t, map[string]interface{}{"time": fakeNow, "level": "panic", "message": "test", "foo": "bar"}, last)
+	assert.PanicsWithValue(t, "test 1", func() { Panicf("test %d%v", 1, xlog.F{"foo": "bar"}) })
+	assert.Equal(t, "test 1", (<-o.w)["message"])
+}
+
+func TestStdCtx(t *testing.T) {
+	o := newTestOutput()
+	oldStd := std
+	defer func() { std = oldStd }()
+	SetLogger(New(Config{Output: o, Level: LevelTrace, NowGetter: func() time.Time { return fakeNow }}))
+	ctx := WithFields(NewTraceContext(context.Background(), "t1", "s1"), xlog.F{"a": 1})
+	for level, log := range map[string]func(context.Context, ...interface{}){
+		"trace": TraceCtx,
+		"debug": DebugCtx,
+		"info":  InfoCtx,
+		"warn":  WarnCtx,
+		"error": ErrorCtx,
+	} {
+		log(ctx, "test", xlog.F{"foo": "bar"})
+		last := o.get()
+		assert.Contains(t, last["file"], "std_test.go:", level)
+		delete(last, "file")
+		assert.Equal(t, map[string]interface{}{"time": fakeNow, "level": level, "message": "test", "foo": "bar", "a": 1, "trace_id": "t1", "span_id": "s1"}, last)
+	}
+	oldExit := exit1
+	exited := false
+	exit1 = func() { exited = true }
+	defer func() { exit1 = oldExit }()
+	FatalCtx(ctx, "test")
+	assert.True(t, exited)
+	assert.Equal(t, 1, o.get()["a"])
+	assert.PanicsWithValue(t, "test", func() { PanicCtx(ctx, "test") })
+	assert.Equal(t, 1, o.get()["a"])
+}
+
+func TestStdCtxOtherLogger(t *testing.T) {
+	o := newTestOutput()
+	oldStd := std
+	defer func() { std = oldStd }()
+	SetLogger(wrappedLogger{New(Config{Output: o})})
+	InfoCtx(WithFields(context.Background(), xlog.F{"a": 1}), "test", xlog.F{"foo": "bar"})
+	last := o.get()
+	assert.Contains(t, last["file"], "std_test.go:")
+	assert.Equal(t, 1, last["a"])
+	assert.Equal(t, "bar", last["foo"])
+}
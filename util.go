@@ -1,24 +1,37 @@
 package xlog
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/xlog"
 )
 
-type color int
+// Color is an ANSI color code used by the console output.
+type Color int
 
+// Console colors
 const (
-	red    color = 31
-	green  color = 32
-	yellow color = 33
-	blue   color = 34
-	gray   color = 37
+	ColorRed     Color = 31
+	ColorGreen   Color = 32
+	ColorYellow  Color = 33
+	ColorBlue    Color = 34
+	ColorMagenta Color = 35
+	ColorCyan    Color = 36
+	ColorGray    Color = 37
 )
 
-func colorPrint(w io.Writer, s string, c color) {
-	w.Write([]byte{0x1b, '[', byte('0' + c/10), byte('0' + c%10), 'm'})
+func colorPrint(w io.Writer, s string, c Color) {
+	w.Write([]byte("\x1b[" + strconv.Itoa(int(c)) + "m"))
 	w.Write([]byte(s))
 	w.Write([]byte("\x1b[0m"))
 }
@@ -27,27 +40,255 @@ func needsQuotedValueRune(r rune) bool {
 	return r <= ' ' || r == '=' || r == '"'
 }
 
-// writeValue writes a value on the writer in a logfmt compatible way
-func writeValue(w io.Writer, v interface{}) (err error) {
+// writeKeyValue writes a space followed by key=value in logfmt format. Errors
+// are followed by their details and namespaces are flattened using dotted keys.
+// If keyColor is true, keys are colored as in the console output.
+func writeKeyValue(buf *bytes.Buffer, key string, v interface{}, keyColor bool) error {
+	if ns, ok := v.(Namespace); ok {
+		keys := make([]string, 0, len(ns))
+		for k := range ns {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := writeKeyValue(buf, key+"."+k, ns[k], keyColor); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	buf.WriteByte(' ')
+	if keyColor {
+		colorPrint(buf, key, ColorGreen)
+	} else {
+		buf.WriteString(key)
+	}
+	buf.WriteByte('=')
+	if err := writeValue(buf, v); err != nil {
+		return err
+	}
+	if e, ok := v.(error); ok {
+		return writeErrorDetails(buf, key, e, keyColor)
+	}
+	return nil
+}
+
+// writeValue writes a value on the buffer in a logfmt compatible way
+func writeValue(buf *bytes.Buffer, v interface{}) (err error) {
 	switch v := v.(type) {
 	case nil:
-		_, err = w.Write([]byte("null"))
+		buf.WriteString("null")
 	case string:
-		if strings.IndexFunc(v, needsQuotedValueRune) != -1 {
-			var b []byte
-			b, err = json.Marshal(v)
-			if err == nil {
-				w.Write(b)
-			}
-		} else {
-			_, err = w.Write([]byte(v))
-		}
+		err = writeString(buf, v)
+	case int:
+		var b [32]byte
+		buf.Write(strconv.AppendInt(b[:0], int64(v), 10))
+	case int64:
+		var b [32]byte
+		buf.Write(strconv.AppendInt(b[:0], v, 10))
+	case bool:
+		var b [8]byte
+		buf.Write(strconv.AppendBool(b[:0], v))
+	case time.Time:
+		writeTime(buf, v)
 	case error:
 		s := v.Error()
-		err = writeValue(w, s)
+		err = writeValue(buf, s)
 	default:
 		s := fmt.Sprint(v)
-		err = writeValue(w, s)
+		err = writeValue(buf, s)
+	}
+	return
+}
+
+// timeLayout is the layout of time.Time.String, without the monotonic clock
+// reading.
+const timeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// writeTime writes t quoted on the buffer in a logfmt compatible way.
+func writeTime(buf *bytes.Buffer, t time.Time) {
+	var b [64]byte
+	buf.WriteByte('"')
+	buf.Write(t.AppendFormat(b[:0], timeLayout))
+	buf.WriteByte('"')
+}
+
+// writeString writes a string on the writer in a logfmt compatible way
+func writeString(w io.Writer, s string) (err error) {
+	if strings.IndexFunc(s, needsQuotedValueRune) != -1 {
+		var b []byte
+		b, err = json.Marshal(s)
+		if err == nil {
+			w.Write(b)
+		}
+	} else {
+		_, err = io.WriteString(w, s)
 	}
 	return
 }
+
+// writeField writes the value of a typed field on the buffer in a logfmt
+// compatible way. The output is the same as writeValue with f.Value().
+func writeField(buf *bytes.Buffer, f Field) error {
+	var b [32]byte
+	switch f.Type {
+	case StringType:
+		return writeString(buf, f.String)
+	case Int64Type:
+		buf.Write(strconv.AppendInt(b[:0], f.Integer, 10))
+	case Uint64Type:
+		buf.Write(strconv.AppendUint(b[:0], uint64(f.Integer), 10))
+	case Float64Type:
+		buf.Write(strconv.AppendFloat(b[:0], math.Float64frombits(uint64(f.Integer)), 'g', -1, 64))
+	case BoolType:
+		buf.Write(strconv.AppendBool(b[:0], f.Integer == 1))
+	case TimeType:
+		writeTime(buf, f.Time)
+	case DurationType:
+		buf.WriteString(time.Duration(f.Integer).String())
+	case CallerType:
+		file := path.Base(f.String)
+		if strings.IndexFunc(file, needsQuotedValueRune) != -1 {
+			return writeString(buf, f.Value().(string))
+		}
+		buf.WriteString(file)
+		buf.WriteByte(':')
+		buf.Write(strconv.AppendInt(b[:0], f.Integer, 10))
+	default:
+		return writeValue(buf, f.Interface)
+	}
+	return nil
+}
+
+// findField returns the field with the given key or a field with a nil value.
+func findField(fields []Field, key string) Field {
+	for _, f := range fields {
+		if f.Key == key {
+			return f
+		}
+	}
+	return Field{Key: key, Type: InterfaceType}
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s as a JSON string to b, escaping it the same way as
+// encoding/json.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// appendJSONField appends the value of a typed field to b in JSON. The output
+// is the same as encoding/json with f.Value().
+func appendJSONField(b []byte, f Field) ([]byte, error) {
+	switch f.Type {
+	case StringType:
+		return appendJSONString(b, f.String), nil
+	case Int64Type, DurationType:
+		return strconv.AppendInt(b, f.Integer, 10), nil
+	case Uint64Type:
+		return strconv.AppendUint(b, uint64(f.Integer), 10), nil
+	case Float64Type:
+		v := math.Float64frombits(uint64(f.Integer))
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			break
+		}
+		// Same format selection as encoding/json
+		format := byte('f')
+		if abs := math.Abs(v); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+			format = 'e'
+		}
+		b = strconv.AppendFloat(b, v, format, -1, 64)
+		if format == 'e' {
+			// Clean up e-09 to e-9
+			if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+				b[n-2] = b[n-1]
+				b = b[:n-1]
+			}
+		}
+		return b, nil
+	case BoolType:
+		return strconv.AppendBool(b, f.Integer == 1), nil
+	case TimeType:
+		if y := f.Time.Year(); y < 0 || y >= 10000 {
+			break
+		}
+		b = append(b, '"')
+		b = f.Time.AppendFormat(b, time.RFC3339Nano)
+		return append(b, '"'), nil
+	case CallerType:
+		b = appendJSONString(b, path.Base(f.String))
+		b = append(b[:len(b)-1], ':')
+		b = strconv.AppendInt(b, f.Integer, 10)
+		return append(b, '"'), nil
+	}
+	v := f.Value()
+	switch t := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case string:
+		return appendJSONString(b, t), nil
+	case int:
+		return strconv.AppendInt(b, int64(t), 10), nil
+	case int64:
+		return strconv.AppendInt(b, t, 10), nil
+	case bool:
+		return strconv.AppendBool(b, t), nil
+	case error:
+		v = errorObject(t)
+	case Namespace:
+		v = withErrorObjects(t)
+	case xlog.F:
+		v = withErrorObjects(t)
+	case map[string]interface{}:
+		v = withErrorObjects(t)
+	}
+	j, err := json.Marshal(v)
+	if err != nil {
+		return b, err
+	}
+	return append(b, j...), nil
+}
@@ -11,19 +11,25 @@ import (
 
 func TestColorPrint(t *testing.T) {
 	buf := &bytes.Buffer{}
-	colorPrint(buf, "test", red)
+	colorPrint(buf, "test", ColorRed)
 	assert.Equal(t, "\x1b[31mtest\x1b[0m", buf.String())
 	buf.Reset()
-	colorPrint(buf, "test", green)
+	colorPrint(buf, "test", ColorGreen)
 	assert.Equal(t, "\x1b[32mtest\x1b[0m", buf.String())
 	buf.Reset()
-	colorPrint(buf, "test", yellow)
+	colorPrint(buf, "test", ColorYellow)
 	assert.Equal(t, "\x1b[33mtest\x1b[0m", buf.String())
 	buf.Reset()
-	colorPrint(buf, "test", blue)
+	colorPrint(buf, "test", ColorBlue)
 	assert.Equal(t, "\x1b[34mtest\x1b[0m", buf.String())
 	buf.Reset()
-	colorPrint(buf, "test", gray)
+	colorPrint(buf, "test", Color(1))
+	assert.Equal(t, "\x1b[1mtest\x1b[0m", buf.String())
+	buf.Reset()
+	colorPrint(buf, "test", Color(105))
+	assert.Equal(t, "\x1b[105mtest\x1b[0m", buf.String())
+	buf.Reset()
+	colorPrint(buf, "test", ColorGray)
 	assert.Equal(t, "\x1b[37mtest\x1b[0m", buf.String())
 }
 
@@ -52,5 +58,10 @@ func TestWriteValue(t *testing.T) {
 	assert.Equal(t, `"foo\nbar"`, write("foo\nbar"))
 	assert.Equal(t, `null`, write(nil))
 	assert.Equal(t, `"2000-01-02 03:04:05 +0000 UTC"`, write(time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)))
+	assert.Equal(t, `"2000-01-02 03:04:05.5 +0000 UTC"`, write(time.Date(2000, 1, 2, 3, 4, 5, 5e8, time.UTC)))
+	assert.NotContains(t, write(time.Now()), "m=")
+	assert.Equal(t, `-1`, write(-1))
+	assert.Equal(t, `2`, write(int64(2)))
+	assert.Equal(t, `true`, write(true))
 	assert.Equal(t, `"error \"with quote\""`, write(errors.New(`error "with quote"`)))
 }
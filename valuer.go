@@ -0,0 +1,31 @@
+package xlog
+
+import (
+	"fmt"
+)
+
+// LogValuer is implemented by types controlling their logged representation,
+// like a masked card number or a money amount logged as an object. The value
+// returned by LogValue is used in place of the field value by all outputs.
+//
+// LogValue is called on the logging go routine when the message passes the
+// level check. It may return another LogValuer, in which case it is resolved
+// again up to maxLogValueDepth times.
+type LogValuer interface {
+	LogValue() interface{}
+}
+
+// maxLogValueDepth limits the number of LogValue calls for a single field value
+// so a LogValuer returning itself does not loop forever.
+const maxLogValueDepth = 10
+
+// logValue calls v.LogValue. A panic in LogValue, like when it is called on a
+// nil pointer, is returned as an error value.
+func logValue(v LogValuer) (r interface{}) {
+	defer func() {
+		if err := recover(); err != nil {
+			r = fmt.Errorf("LogValue panicked on %T: %v", v, err)
+		}
+	}()
+	return v.LogValue()
+}
@@ -0,0 +1,95 @@
+package xlog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rs/xlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMoney struct {
+	amount   int64
+	currency string
+}
+
+func (m testMoney) LogValue() interface{} {
+	return xlog.F{"amount": m.amount, "currency": m.currency}
+}
+
+type testCardToken string
+
+func (c testCardToken) LogValue() interface{} {
+	return "****" + string(c[len(c)-4:])
+}
+
+type testRecursiveValuer struct{}
+
+func (v testRecursiveValuer) LogValue() interface{} {
+	return v
+}
+
+type testPtrValuer struct {
+	name string
+}
+
+func (v *testPtrValuer) LogValue() interface{} {
+	return v.name
+}
+
+type testChainValuer struct{}
+
+func (testChainValuer) LogValue() interface{} {
+	return LazyValue(func() interface{} { return testCardToken("4242424242424242") })
+}
+
+func TestResolveValue(t *testing.T) {
+	v, ok := resolveValue("foo", true)
+	assert.False(t, ok)
+	assert.Equal(t, "foo", v)
+
+	v, ok = resolveValue(testCardToken("4242424242424242"), false)
+	assert.True(t, ok)
+	assert.Equal(t, "****4242", v)
+
+	v, ok = resolveValue(testChainValuer{}, false)
+	assert.True(t, ok)
+	assert.Equal(t, "****4242", v)
+
+	v, ok = resolveValue(testRecursiveValuer{}, false)
+	assert.True(t, ok)
+	assert.EqualError(t, v.(error), "LogValue called too many times on xlog.testRecursiveValuer")
+
+	var nilValuer *testPtrValuer
+	v, ok = resolveValue(nilValuer, false)
+	assert.True(t, ok)
+	if err, ok := v.(error); assert.True(t, ok) {
+		assert.Contains(t, err.Error(), "LogValue panicked on *xlog.testPtrValuer")
+	}
+}
+
+func TestLogValuer(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, Fields: xlog.F{"card": testCardToken("4242424242424242")}})
+	l.Info("test", xlog.F{"price": testMoney{1000, "JPY"}})
+	last := o.get()
+	assert.Equal(t, "****4242", last["card"])
+	assert.Equal(t, xlog.F{"amount": int64(1000), "currency": "JPY"}, last["price"])
+
+	l.InfoEvent().Interface("price", testMoney{1000, "JPY"}).Msg("test")
+	last = o.get()
+	assert.Equal(t, "****4242", last["card"])
+	assert.Equal(t, xlog.F{"amount": int64(1000), "currency": "JPY"}, last["price"])
+}
+
+func TestLogValuerOutputs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(Config{Output: NewJSONOutput(buf), DisableCaller: true})
+	l.InfoEvent().Interface("card", testCardToken("4242424242424242")).Msg("test")
+	assert.Contains(t, buf.String(), `"card":"****4242"`)
+
+	buf.Reset()
+	l = New(Config{Output: NewLogfmtOutput(buf), DisableCaller: true})
+	l.Info("test", xlog.F{"card": testCardToken("4242424242424242")})
+	assert.Contains(t, buf.String(), "card=****4242")
+}
@@ -1,4 +1,4 @@
-// Package xlog is a logger coupled with HTTP net/context aware middleware.
+// Package xlog is a logger coupled with HTTP context aware middleware.
 //
 // Unlike most loggers, xlog will never block your application because one its
 // outputs is lagging. The log commands are connected to their outputs through
@@ -17,17 +17,15 @@
 //     - Drops message rather than blocking execution
 //     - Easy access logging thru github.com/rs/xaccess
 //
-// It works best in combination with github.com/rs/xhandler.
+// It requires Go 1.7+ as it relies on the standard context package.
 package xlog // import "github.com/kanmu/xlog"
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"path"
-	"runtime"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -43,8 +41,29 @@ type Logger interface {
 	// SetField sets a field on the logger's context. All future messages on this logger
 	// will have this field set.
 	SetField(name string, value interface{})
-	// GetFields returns all the fields set on the logger
+	// GetFields returns a snapshot of all the fields set on the logger
 	GetFields() xlog.F
+	// With returns a child logger with the given fields added to the ones of
+	// its parent. Fields set on the child are not visible to the parent.
+	With(fields xlog.F) Logger
+	// Named returns a child logger with the name appended to the parent's name
+	// using a dot as separator. The full name is set in the KeyLogger field and
+	// is used to select the level from Config.Levels.
+	Named(name string) Logger
+	// Group returns a child logger nesting the fields set on it, and on its
+	// messages, under the name. Fields of the parent are kept at their level.
+	// See Namespace for how outputs render nested fields.
+	Group(name string) Logger
+	// AddCallerSkip returns a child logger skipping n more stack frames when
+	// recording the caller, so helpers wrapping the logger report the location
+	// of their own caller.
+	AddCallerSkip(n int) Logger
+	// Trace logs a trace message. If last parameter is a map[string]string, it's content
+	// is added as fields to the message.
+	Trace(v ...interface{})
+	// Tracef logs a trace message with format. If last parameter is a map[string]string,
+	// it's content is added as fields to the message.
+	Tracef(format string, v ...interface{})
 	// Debug logs a debug message. If last parameter is a map[string]string, it's content
 	// is added as fields to the message.
 	Debug(v ...interface{})
@@ -75,8 +94,55 @@ type Logger interface {
 	// Fatalf logs an error message with format followed by a call to ox.Exit(1). If last
 	// parameter is a map[string]string, it's content is added as fields to the message.
 	Fatalf(format string, v ...interface{})
+	// Panic logs an error message followed by a call to panic with the message. If last
+	// parameter is a map[string]string, it's content is added as fields to the message.
+	Panic(v ...interface{})
+	// Panicf logs an error message with format followed by a call to panic with the
+	// message. If last parameter is a map[string]string, it's content is added as fields
+	// to the message.
+	Panicf(format string, v ...interface{})
+	// TraceCtx logs a trace message with the fields extracted from ctx: the request
+	// id, the trace and span ids, the fields stored by WithFields and the ones
+	// returned by the ContextExtractors of the Config. If last parameter is a
+	// map[string]string, it's content is added as fields to the message.
+	TraceCtx(ctx context.Context, v ...interface{})
+	// DebugCtx logs a debug message with the fields extracted from ctx. See TraceCtx.
+	DebugCtx(ctx context.Context, v ...interface{})
+	// InfoCtx logs an info message with the fields extracted from ctx. See TraceCtx.
+	InfoCtx(ctx context.Context, v ...interface{})
+	// WarnCtx logs a warning message with the fields extracted from ctx. See TraceCtx.
+	WarnCtx(ctx context.Context, v ...interface{})
+	// ErrorCtx logs an error message with the fields extracted from ctx. See TraceCtx.
+	ErrorCtx(ctx context.Context, v ...interface{})
+	// FatalCtx logs an error message with the fields extracted from ctx followed by
+	// a call to os.Exit(1). See TraceCtx.
+	FatalCtx(ctx context.Context, v ...interface{})
+	// PanicCtx logs an error message with the fields extracted from ctx followed by
+	// a call to panic with the message. See TraceCtx.
+	PanicCtx(ctx context.Context, v ...interface{})
+	// TraceEvent returns a trace event. See DebugEvent.
+	TraceEvent() *Event
+	// DebugEvent returns a debug event to add typed fields to. The event is sent
+	// using its Msg or Msgf method. If the level is disabled, nil is returned,
+	// on which all Event methods are no-op.
+	DebugEvent() *Event
+	// InfoEvent returns an info event. See DebugEvent.
+	InfoEvent() *Event
+	// WarnEvent returns a warning event. See DebugEvent.
+	WarnEvent() *Event
+	// ErrorEvent returns an error event. See DebugEvent.
+	ErrorEvent() *Event
+	// FatalEvent returns a fatal event. Sending the event is followed by a call
+	// to os.Exit(1). See DebugEvent.
+	FatalEvent() *Event
+	// PanicEvent returns a panic event. Sending the event is followed by a call
+	// to panic with the message. See DebugEvent.
+	PanicEvent() *Event
 	// Output mimics std logger interface
 	Output(calldepth int, s string) error
+	// Enabled returns true if a message at the given level would be sent to
+	// the output. Use it to guard expensive field building.
+	Enabled(level xlog.Level) bool
 	// OutputF outputs message with fields.
 	OutputF(level xlog.Level, calldepth int, msg string, fields map[string]interface{})
 	// Now retrieves the current time from the associated "now getter"
@@ -92,6 +158,14 @@ type LoggerCopier interface {
 type Config struct {
 	// Level is the maximum level to output, logs with lower level are discarded.
 	Level xlog.Level
+	// AtomicLevel, if set, takes precedence over Level. It is read on every
+	// message so the level of all the loggers created with this config can be
+	// changed at runtime.
+	AtomicLevel *AtomicLevel
+	// Levels defines level overrides for named loggers. The rule with the
+	// longest name matching the logger's name is used. Named loggers with no
+	// matching rule use Level or AtomicLevel.
+	Levels LevelRules
 	// Fields defines default fields to use with all messages.
 	Fields map[string]interface{}
 	// Output to use to write log messages to.
@@ -106,22 +180,95 @@ type Config struct {
 	DisablePooling bool
 	// NowGetter points to a function that returns the current time.
 	NowGetter func() time.Time
+	// Hooks are run in order on every message before it is sent to the output.
+	Hooks []Hook
+	// StackTrace adds a stack trace in the KeyStack field of messages with a
+	// level greater or equal to StackTraceLevel. If an error with a stack trace
+	// (like the ones created by github.com/pkg/errors) is found in the message
+	// fields, its stack is used instead of the one of the log call.
+	StackTrace bool
+	// StackTraceLevel is the minimum level of messages getting a stack trace
+	// when StackTrace is enabled. It defaults to xlog.LevelError when nil.
+	StackTraceLevel *xlog.Level
+	// DisableCaller disables the capture of the location of the log call in
+	// the KeyFile field.
+	DisableCaller bool
+	// CallerModulePath records the path of the file relative to the main module
+	// (i.e.: internal/db/conn.go:42) instead of the file base name. The main
+	// module is read from the build info, only available with Go 1.12+, and
+	// files keep their full import path without it.
+	CallerModulePath bool
+	// CallerFunc records the name of the calling function in the KeyFunc field.
+	CallerFunc bool
+	// FieldConflict defines which field is kept when a message field has the
+	// same key as a logger field. Fields set by the logger, like time or level,
+	// are never overwritten: conflicting fields are moved under the KeyFields
+	// namespace.
+	FieldConflict ConflictPolicy
+	// WarnConflicts reports every field conflict on the stderr. Use it while
+	// developing to find conflicting keys.
+	WarnConflicts bool
+	// SnapshotFields deep copies the maps, slices, arrays and pointers found in
+	// the fields of every message before it is sent, so the caller can modify
+	// them once the log call returned while an OutputChannel still holds the
+	// message. This option adds a copy cost to every message with such values.
+	SnapshotFields bool
+	// FieldKeys defines the names of the fields set by the logger. Empty names
+	// use the default ones. When set, outputs must be created with the same keys
+	// (i.e.: NewLogfmtOutputKeys) so they find the time, level and message.
+	FieldKeys FieldKeys
+	// ContextExtractors return fields to add to the messages sent with the Ctx
+	// methods, like InfoCtx, in addition to the request id, the trace ids and
+	// the fields stored in the context.
+	ContextExtractors []ContextExtractor
 }
 
 type logger struct {
-	level          xlog.Level
-	output         xlog.Output
+	level       xlog.Level
+	atomicLevel *AtomicLevel
+	levels      LevelRules
+	name        string
+	output      xlog.Output
+	// fields is copy-on-write: once published, a fields map is never mutated
+	// so it can be read outside of mu.
 	fields         xlog.F
+	mu             sync.RWMutex
 	disablePooling bool
 	now            func() time.Time
+	hooks          []Hook
+	// stackTrace enables stack traces for messages at or above stackTraceLevel
+	stackTrace      bool
+	stackTraceLevel xlog.Level
+	caller          callerConfig
+	snapshot        bool
+	conflict        ConflictPolicy
+	warnConflicts   bool
+	// keys is never modified once set so it can be shared by clones and events
+	keys *FieldKeys
+	// group is the path of the namespace new fields are set in
+	group []string
+	// ctxFields are the fields stored in the context the logger was obtained
+	// from. They are merged with the logger fields when a message is sent.
+	ctxFields  xlog.F
+	extractors []ContextExtractor
 }
 
-// Common field names for log messages.
+// Default field names for log messages. They are read when a logger or an
+// output is created, use Config.FieldKeys to change them for a given logger.
 var (
 	KeyTime    = "time"
 	KeyMessage = "message"
 	KeyLevel   = "level"
 	KeyFile    = "file"
+	KeyFunc    = "func"
+	KeyLogger  = "logger"
+	KeyError   = "error"
+	KeyStack   = "stack"
+	KeyFields  = "fields"
+
+	KeyRequestID = "req_id"
+	KeyTraceID   = "trace_id"
+	KeySpanID    = "span_id"
 )
 
 var exit1 = func() { os.Exit(1) }
@@ -148,6 +295,8 @@ func New(c interface{}) Logger {
 		} else {
 			l = loggerPool.Get().(*logger)
 		}
+		keys := DefaultFieldKeys()
+		l.keys = &keys
 		l.level = c.Level
 		l.output = c.Output
 		if l.output == nil {
@@ -164,10 +313,14 @@ func New(c interface{}) Logger {
 		} else {
 			l = loggerPool.Get().(*logger)
 		}
+		keys := c.FieldKeys.withDefaults()
+		l.keys = &keys
 		l.level = c.Level
+		l.atomicLevel = c.AtomicLevel
+		l.levels = c.Levels
 		l.output = c.Output
 		if l.output == nil {
-			l.output = NewOutputChannel(NewConsoleOutput())
+			l.output = NewOutputChannel(NewConsoleOutputKeys(os.Stderr, NewLogfmtOutputKeys(os.Stderr, keys), keys))
 		}
 		for k, v := range c.Fields {
 			l.SetField(k, v)
@@ -178,6 +331,21 @@ func New(c interface{}) Logger {
 		} else {
 			l.now = time.Now
 		}
+		l.hooks = c.Hooks
+		l.stackTrace = c.StackTrace
+		l.stackTraceLevel = xlog.LevelError
+		if c.StackTraceLevel != nil {
+			l.stackTraceLevel = *c.StackTraceLevel
+		}
+		l.caller = callerConfig{
+			disable:    c.DisableCaller,
+			modulePath: c.CallerModulePath,
+			funcName:   c.CallerFunc,
+		}
+		l.snapshot = c.SnapshotFields
+		l.conflict = c.FieldConflict
+		l.warnConflicts = c.WarnConflicts
+		l.extractors = c.ContextExtractors
 	}
 	return l
 }
@@ -193,18 +361,90 @@ func Copy(l Logger) Logger {
 
 // Copy returns a copy of the logger
 func (l *logger) Copy() Logger {
-	l2 := &logger{
-		level:          l.level,
-		output:         l.output,
-		fields:         map[string]interface{}{},
-		disablePooling: l.disablePooling,
-	}
-	for k, v := range l.fields {
+	l2 := l.clone()
+	l2.fields = map[string]interface{}{}
+	l2.disablePooling = l.disablePooling
+	for k, v := range l.getFields() {
 		l2.fields[k] = v
 	}
 	return l2
 }
 
+// With implements Logger interface
+func (l *logger) With(fields xlog.F) Logger {
+	return l.with(l.group, fields)
+}
+
+// with returns a child logger with fields added in the namespace at path.
+func (l *logger) with(path []string, fields xlog.F) *logger {
+	parent := l.getFields()
+	if len(fields) > 0 {
+		parent = mergeFields(parent, path, fields)
+	}
+	c := l.clone()
+	c.fields = parent
+	// The child is never returned to the pool as it may outlive the parent's
+	// request handler.
+	c.disablePooling = true
+	return c
+}
+
+// clone returns a new logger with the same configuration as l and no fields.
+func (l *logger) clone() *logger {
+	return &logger{
+		level:           l.level,
+		atomicLevel:     l.atomicLevel,
+		levels:          l.levels,
+		name:            l.name,
+		output:          l.output,
+		now:             l.now,
+		hooks:           l.hooks,
+		stackTrace:      l.stackTrace,
+		stackTraceLevel: l.stackTraceLevel,
+		caller:          l.caller,
+		snapshot:        l.snapshot,
+		conflict:        l.conflict,
+		warnConflicts:   l.warnConflicts,
+		keys:            l.keys,
+		group:           l.group,
+		ctxFields:       l.ctxFields,
+		extractors:      l.extractors,
+	}
+}
+
+// withContext returns a logger sending the fields stored in a context with
+// its messages. The fields of l are copied so the returned logger remains
+// valid once l is returned to the pool.
+func (l *logger) withContext(fields xlog.F) *logger {
+	c := l.clone()
+	c.fields = l.getFields()
+	c.ctxFields = mergeFields(l.ctxFields, nil, fields)
+	c.disablePooling = true
+	return c
+}
+
+// Named implements Logger interface
+func (l *logger) Named(name string) Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	c := l.with(nil, xlog.F{l.keys.Logger: name})
+	c.name = name
+	if a := l.levels.match(name); a != nil {
+		c.atomicLevel = a
+	}
+	return c
+}
+
+// AddCallerSkip implements Logger interface
+func (l *logger) AddCallerSkip(n int) Logger {
+	c := l.clone()
+	c.fields = l.getFields()
+	c.disablePooling = true
+	c.caller.skip += n
+	return c
+}
+
 // Now returns the current time
 func (l *logger) Now() time.Time {
 	return l.now()
@@ -214,36 +454,88 @@ func (l *logger) Now() time.Time {
 func (l *logger) close() {
 	if !l.disablePooling {
 		l.level = 0
+		l.atomicLevel = nil
+		l.levels = nil
+		l.name = ""
+		l.hooks = nil
+		l.stackTrace = false
+		l.caller = callerConfig{}
+		l.snapshot = false
+		l.conflict = PreferContext
+		l.warnConflicts = false
+		l.keys = nil
+		l.group = nil
+		l.ctxFields = nil
+		l.extractors = nil
 		l.output = nil
+		l.mu.Lock()
 		l.fields = nil
+		l.mu.Unlock()
 		loggerPool.Put(l)
 	}
 }
 
+// minLevel returns the minimum severity a message must have to be sent.
+func (l *logger) minLevel() xlog.Level {
+	if l.atomicLevel != nil {
+		return severity(l.atomicLevel.Level())
+	}
+	return severity(l.level)
+}
+
 func (l *logger) send(level xlog.Level, calldepth int, msg string, fields map[string]interface{}) {
-	if level < l.level || l.output == nil {
+	if !l.Enabled(level) {
 		return
 	}
-	data := make(map[string]interface{}, 4+len(fields)+len(l.fields))
-	data[KeyTime] = l.Now()
-	data[KeyLevel] = level.String()
-	data[KeyMessage] = msg
-	if _, file, line, ok := runtime.Caller(calldepth); ok {
-		data[KeyFile] = path.Base(file) + ":" + strconv.FormatInt(int64(line), 10)
+	l.sendWith(level, calldepth+1, msg, fields, l.logFields())
+}
+
+// sendWith sends a message with the lfields logger fields.
+func (l *logger) sendWith(level xlog.Level, calldepth int, msg string, fields, lfields map[string]interface{}) {
+	data := make(map[string]interface{}, 4+len(fields)+len(lfields))
+	var stack string
+	if l.stackEnabled(level) {
+		if stack = fieldsStack(fields); stack == "" {
+			stack = captureStack(calldepth + l.caller.skip)
+		}
+	}
+	if len(l.group) > 0 && len(fields) > 0 {
+		fields = mergeFields(nil, l.group, fields)
 	}
-	for k, v := range fields {
-		data[k] = v
+	renamed := l.addFields(data, fields, lfields)
+	// Fields set by the logger are set last so they are never overwritten
+	renamed = l.setReserved(data, renamed, l.keys.Time, l.Now())
+	renamed = l.setReserved(data, renamed, l.keys.Level, levelName(level))
+	renamed = l.setReserved(data, renamed, l.keys.Message, msg)
+	var caller [2]Field
+	for _, f := range l.caller.appendCaller(caller[:0], l.keys, l.caller.callerPC(calldepth)) {
+		renamed = l.setReserved(data, renamed, f.Key, f.Value())
 	}
-	if l.fields != nil {
-		for k, v := range l.fields {
-			data[k] = v
-		}
+	if stack != "" {
+		renamed = l.setReserved(data, renamed, l.keys.Stack, stack)
+	}
+	setRenamed(data, l.keys.Fields, renamed)
+	resolveFields(data, !l.deferLazy())
+	if l.snapshot {
+		snapshotFields(data)
+	}
+	if !l.runHooks(level, msg, data) {
+		return
 	}
-	if err := l.output.Write(data); err != nil {
+	if err := l.writeFields(level, data); err != nil && err != ErrBufferFull {
 		critialLogger.Print("send error: ", err.Error())
 	}
 }
 
+// writeFields sends the fields of a message to the output. Messages dropped by
+// an OutputChannel are reported by its summaries rather than on the stderr.
+func (l *logger) writeFields(level xlog.Level, fields map[string]interface{}) error {
+	if oc, ok := l.output.(*OutputChannel); ok {
+		return oc.enqueue(message{fields: fields, level: level, keys: l.keys})
+	}
+	return l.output.Write(fields)
+}
+
 func extractFields(v *[]interface{}) map[string]interface{} {
 	if l := len(*v); l > 0 {
 		if f, ok := (*v)[l-1].(map[string]interface{}); ok {
@@ -258,17 +550,54 @@ func extractFields(v *[]interface{}) map[string]interface{} {
 	return nil
 }
 
+// getFields returns the current fields map. The returned map must not be
+// modified as it may be shared with concurrent readers.
+func (l *logger) getFields() xlog.F {
+	l.mu.RLock()
+	f := l.fields
+	l.mu.RUnlock()
+	return f
+}
+
+// logFields returns the fields sent with every message: the logger fields
+// merged with the context fields, the latter taking precedence.
+func (l *logger) logFields() xlog.F {
+	f := l.getFields()
+	if len(l.ctxFields) > 0 {
+		f = mergeFields(f, nil, l.ctxFields)
+	}
+	return f
+}
+
 // SetField implements Logger interface
 func (l *logger) SetField(name string, value interface{}) {
-	if l.fields == nil {
-		l.fields = map[string]interface{}{}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.group) > 0 {
+		l.fields = mergeFields(l.fields, l.group, xlog.F{name: value})
+		return
+	}
+	fields := make(xlog.F, len(l.fields)+1)
+	for k, v := range l.fields {
+		fields[k] = v
 	}
-	l.fields[name] = value
+	fields[name] = value
+	l.fields = fields
 }
 
 // GetFields implements Logger interface
 func (l *logger) GetFields() xlog.F {
-	return l.fields
+	fields := l.getFields()
+	f := make(xlog.F, len(fields))
+	for k, v := range fields {
+		f[k] = v
+	}
+	return f
+}
+
+// Enabled implements Logger interface
+func (l *logger) Enabled(level xlog.Level) bool {
+	return severity(level) >= l.minLevel() && l.output != nil
 }
 
 // Output implements Logger interface
@@ -276,44 +605,83 @@ func (l *logger) OutputF(level xlog.Level, calldepth int, msg string, fields map
 	l.send(level, calldepth+1, msg, fields)
 }
 
+// Trace implements Logger interface
+func (l *logger) Trace(v ...interface{}) {
+	if !l.Enabled(LevelTrace) {
+		return
+	}
+	f := extractFields(&v)
+	l.send(LevelTrace, 2, fmt.Sprint(v...), f)
+}
+
+// Tracef implements Logger interface
+func (l *logger) Tracef(format string, v ...interface{}) {
+	if !l.Enabled(LevelTrace) {
+		return
+	}
+	f := extractFields(&v)
+	l.send(LevelTrace, 2, fmt.Sprintf(format, v...), f)
+}
+
 // Debug implements Logger interface
 func (l *logger) Debug(v ...interface{}) {
+	if !l.Enabled(xlog.LevelDebug) {
+		return
+	}
 	f := extractFields(&v)
 	l.send(xlog.LevelDebug, 2, fmt.Sprint(v...), f)
 }
 
 // Debugf implements Logger interface
 func (l *logger) Debugf(format string, v ...interface{}) {
+	if !l.Enabled(xlog.LevelDebug) {
+		return
+	}
 	f := extractFields(&v)
 	l.send(xlog.LevelDebug, 2, fmt.Sprintf(format, v...), f)
 }
 
 // Info implements Logger interface
 func (l *logger) Info(v ...interface{}) {
+	if !l.Enabled(xlog.LevelInfo) {
+		return
+	}
 	f := extractFields(&v)
 	l.send(xlog.LevelInfo, 2, fmt.Sprint(v...), f)
 }
 
 // Infof implements Logger interface
 func (l *logger) Infof(format string, v ...interface{}) {
+	if !l.Enabled(xlog.LevelInfo) {
+		return
+	}
 	f := extractFields(&v)
 	l.send(xlog.LevelInfo, 2, fmt.Sprintf(format, v...), f)
 }
 
 // Warn implements Logger interface
 func (l *logger) Warn(v ...interface{}) {
+	if !l.Enabled(xlog.LevelWarn) {
+		return
+	}
 	f := extractFields(&v)
 	l.send(xlog.LevelWarn, 2, fmt.Sprint(v...), f)
 }
 
 // Warnf implements Logger interface
 func (l *logger) Warnf(format string, v ...interface{}) {
+	if !l.Enabled(xlog.LevelWarn) {
+		return
+	}
 	f := extractFields(&v)
 	l.send(xlog.LevelWarn, 2, fmt.Sprintf(format, v...), f)
 }
 
 // Error implements Logger interface
 func (l *logger) Error(v ...interface{}) {
+	if !l.Enabled(xlog.LevelError) {
+		return
+	}
 	f := extractFields(&v)
 	l.send(xlog.LevelError, 2, fmt.Sprint(v...), f)
 }
@@ -323,6 +691,9 @@ func (l *logger) Error(v ...interface{}) {
 // Go vet users: you may append %v at the end of you format when using xlog.F{} as a last
 // argument to workaround go vet false alarm.
 func (l *logger) Errorf(format string, v ...interface{}) {
+	if !l.Enabled(xlog.LevelError) {
+		return
+	}
 	f := extractFields(&v)
 	if f != nil {
 		// Let user add a %v at the end of the message when fields are passed to satisfy go vet
@@ -336,8 +707,10 @@ func (l *logger) Errorf(format string, v ...interface{}) {
 
 // Fatal implements Logger interface
 func (l *logger) Fatal(v ...interface{}) {
-	f := extractFields(&v)
-	l.send(xlog.LevelFatal, 2, fmt.Sprint(v...), f)
+	if l.Enabled(xlog.LevelFatal) {
+		f := extractFields(&v)
+		l.send(xlog.LevelFatal, 2, fmt.Sprint(v...), f)
+	}
 	if o, ok := l.output.(*OutputChannel); ok {
 		o.Close()
 	}
@@ -349,6 +722,39 @@ func (l *logger) Fatal(v ...interface{}) {
 // Go vet users: you may append %v at the end of you format when using xlog.F{} as a last
 // argument to workaround go vet false alarm.
 func (l *logger) Fatalf(format string, v ...interface{}) {
+	if l.Enabled(xlog.LevelFatal) {
+		f := extractFields(&v)
+		if f != nil {
+			// Let user add a %v at the end of the message when fields are passed to satisfy go vet
+			l := len(format)
+			if l > 2 && format[l-2] == '%' && format[l-1] == 'v' {
+				format = format[0 : l-2]
+			}
+		}
+		l.send(xlog.LevelFatal, 2, fmt.Sprintf(format, v...), f)
+	}
+	if o, ok := l.output.(*OutputChannel); ok {
+		o.Close()
+	}
+	exit1()
+}
+
+// Panic implements Logger interface
+func (l *logger) Panic(v ...interface{}) {
+	f := extractFields(&v)
+	msg := fmt.Sprint(v...)
+	l.send(LevelPanic, 2, msg, f)
+	if o, ok := l.output.(*OutputChannel); ok {
+		o.Flush()
+	}
+	panic(msg)
+}
+
+// Panicf implements Logger interface
+//
+// Go vet users: you may append %v at the end of you format when using xlog.F{} as a last
+// argument to workaround go vet false alarm.
+func (l *logger) Panicf(format string, v ...interface{}) {
 	f := extractFields(&v)
 	if f != nil {
 		// Let user add a %v at the end of the message when fields are passed to satisfy go vet
@@ -357,11 +763,61 @@ func (l *logger) Fatalf(format string, v ...interface{}) {
 			format = format[0 : l-2]
 		}
 	}
-	l.send(xlog.LevelFatal, 2, fmt.Sprintf(format, v...), f)
+	msg := fmt.Sprintf(format, v...)
+	l.send(LevelPanic, 2, msg, f)
 	if o, ok := l.output.(*OutputChannel); ok {
-		o.Close()
+		o.Flush()
 	}
-	exit1()
+	panic(msg)
+}
+
+// TraceEvent implements Logger interface
+func (l *logger) TraceEvent() *Event {
+	return l.newEvent(LevelTrace)
+}
+
+// DebugEvent implements Logger interface
+func (l *logger) DebugEvent() *Event {
+	return l.newEvent(xlog.LevelDebug)
+}
+
+// InfoEvent implements Logger interface
+func (l *logger) InfoEvent() *Event {
+	return l.newEvent(xlog.LevelInfo)
+}
+
+// WarnEvent implements Logger interface
+func (l *logger) WarnEvent() *Event {
+	return l.newEvent(xlog.LevelWarn)
+}
+
+// ErrorEvent implements Logger interface
+func (l *logger) ErrorEvent() *Event {
+	return l.newEvent(xlog.LevelError)
+}
+
+// FatalEvent implements Logger interface
+func (l *logger) FatalEvent() *Event {
+	e := l.newEvent(xlog.LevelFatal)
+	if e == nil {
+		// The event must still exit once sent
+		e = getEvent()
+		e.level = xlog.LevelFatal
+		e.keys = l.keys
+	}
+	return e
+}
+
+// PanicEvent implements Logger interface
+func (l *logger) PanicEvent() *Event {
+	e := l.newEvent(LevelPanic)
+	if e == nil {
+		// The event must still panic once sent
+		e = getEvent()
+		e.level = LevelPanic
+		e.keys = l.keys
+	}
+	return e
 }
 
 // Write implements io.Writer interface
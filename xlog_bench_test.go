@@ -1,8 +1,10 @@
 package xlog
 
 import (
-	"github.com/rs/xlog"
+	"io/ioutil"
 	"testing"
+
+	"github.com/rs/xlog"
 )
 
 func BenchmarkSend(b *testing.B) {
@@ -13,3 +15,90 @@ func BenchmarkSend(b *testing.B) {
 		l.send(0, 0, "test", xlog.F{"foo": "bar", "bar": "baz"})
 	}
 }
+
+func BenchmarkDisabledDebug(b *testing.B) {
+	l := New(Config{Level: xlog.LevelInfo, Output: Discard, Fields: xlog.F{"a": "b"}}).(*logger)
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Debug("test", 1, "foo")
+	}
+}
+
+func BenchmarkDisabledDebugf(b *testing.B) {
+	l := New(Config{Level: xlog.LevelInfo, Output: Discard, Fields: xlog.F{"a": "b"}}).(*logger)
+	f := xlog.F{"foo": "bar"}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Debugf("test %d %s", 1, "foo", f)
+	}
+}
+
+func BenchmarkDisabledGlobalDebugf(b *testing.B) {
+	oldStd := std
+	defer func() { std = oldStd }()
+	SetLogger(New(Config{Level: xlog.LevelInfo, Output: Discard}))
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Debugf("test %d %s", 1, "foo")
+	}
+}
+
+func BenchmarkEvent(b *testing.B) {
+	l := New(Config{Output: &testEventOutput{}, Fields: xlog.F{"a": "b"}})
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.InfoEvent().Str("foo", "bar").Int("n", i).Msg("test")
+	}
+}
+
+func BenchmarkEventOutput(b *testing.B) {
+	outputs := []struct {
+		name string
+		o    xlog.Output
+	}{
+		{"JSON", NewJSONOutput(ioutil.Discard)},
+		{"Logfmt", NewLogfmtOutput(ioutil.Discard)},
+	}
+	for _, o := range outputs {
+		for _, fields := range []xlog.F{nil, {"a": "b", "count": 1}} {
+			name := o.name
+			if fields != nil {
+				name += "Fields"
+			}
+			b.Run(name, func(b *testing.B) {
+				l := New(Config{Output: o.o, Fields: fields})
+				b.ResetTimer()
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					l.InfoEvent().Str("foo", "bar").Int("n", i).Msg("test")
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkSnapshot(b *testing.B) {
+	fields := xlog.F{
+		"tags":  []string{"a", "b", "c"},
+		"attrs": map[string]int{"a": 1, "b": 2},
+		"user":  &struct{ ID, Name string }{"1", "name"},
+	}
+	for _, snapshot := range []bool{false, true} {
+		name := "Off"
+		if snapshot {
+			name = "On"
+		}
+		b.Run(name, func(b *testing.B) {
+			l := New(Config{Output: Discard, SnapshotFields: snapshot}).(*logger)
+			b.ResetTimer()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.send(xlog.LevelInfo, 0, "test", fields)
+			}
+		})
+	}
+}
@@ -1,4 +1,3 @@
-// +build go1.7
 
 package xlog_test
 
@@ -96,6 +96,7 @@ func TestCopy(t *testing.T) {
 	l2 := Copy(l).(*logger)
 	assert.Equal(t, l.output, l2.output)
 	assert.Equal(t, l.level, l2.level)
+	assert.Equal(t, fakeNow, l2.Now())
 	assert.Equal(t, l.fields, l2.fields)
 	l2.SetField("bar", "baz")
 	assert.Equal(t, xlog.F{"foo": "bar"}, l.fields)
@@ -105,6 +106,25 @@ func TestCopy(t *testing.T) {
 	assert.Equal(t, NopLogger, Copy(nil))
 }
 
+func TestWith(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: o, Fields: xlog.F{"foo": "bar"}, NowGetter: func() time.Time { return fakeNow }}).(*logger)
+	c := l.With(xlog.F{"bar": "baz"}).(*logger)
+	assert.Equal(t, xlog.F{"foo": "bar", "bar": "baz"}, c.GetFields())
+	assert.Equal(t, xlog.F{"foo": "bar"}, l.GetFields())
+	assert.True(t, c.disablePooling)
+
+	c.SetField("baz", "qux")
+	l.SetField("qux", "quux")
+	assert.Equal(t, xlog.F{"foo": "bar", "bar": "baz", "baz": "qux"}, c.GetFields())
+	assert.Equal(t, xlog.F{"foo": "bar", "qux": "quux"}, l.GetFields())
+
+	c.Info("test")
+	last := o.get()
+	delete(last, "file")
+	assert.Equal(t, map[string]interface{}{"time": fakeNow, "level": "info", "message": "test", "foo": "bar", "bar": "baz", "baz": "qux"}, last)
+}
+
 func TestNewDefautOutput(t *testing.T) {
 	L := New(Config{NowGetter: func() time.Time { return fakeNow }})
 	l, ok := L.(*logger)
@@ -191,6 +211,64 @@ func TestGetFields(t *testing.T) {
 	assert.Equal(t, xlog.F{"k": "v"}, l.GetFields())
 }
 
+func TestGetFieldsSnapshot(t *testing.T) {
+	l := New(Config{Output: Discard, Fields: xlog.F{"k": "v"}}).(*logger)
+	f := l.GetFields()
+	f["k"] = "changed"
+	l.SetField("k2", "v2")
+	assert.Equal(t, xlog.F{"k": "changed"}, f)
+	assert.Equal(t, xlog.F{"k": "v", "k2": "v2"}, l.GetFields())
+}
+
+func TestConcurrentFields(t *testing.T) {
+	l := New(Config{Output: Discard, NowGetter: func() time.Time { return fakeNow }}).(*logger)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.SetField("k", j)
+				l.Info("test", xlog.F{"foo": "bar"})
+				l.GetFields()
+				Copy(l).SetField("k", j)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type countStringer struct {
+	n int
+}
+
+func (c *countStringer) String() string {
+	c.n++
+	return "count"
+}
+
+func TestEnabled(t *testing.T) {
+	l := New(Config{Level: xlog.LevelInfo, Output: Discard}).(*logger)
+	assert.False(t, l.Enabled(xlog.LevelDebug))
+	assert.True(t, l.Enabled(xlog.LevelInfo))
+	assert.True(t, l.Enabled(xlog.LevelError))
+	l.output = nil
+	assert.False(t, l.Enabled(xlog.LevelError))
+}
+
+func TestDisabledLevelNoFormat(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Level: xlog.LevelInfo, Output: o}).(*logger)
+	c := &countStringer{}
+	l.Debug(c)
+	l.Debugf("%s", c)
+	assert.Equal(t, 0, c.n)
+	assert.True(t, o.empty())
+	l.Info(c)
+	o.get()
+	assert.Equal(t, 1, c.n)
+}
+
 func TestDebug(t *testing.T) {
 	o := newTestOutput()
 	l := New(Config{Output: o, NowGetter: func() time.Time { return fakeNow }}).(*logger)
@@ -301,6 +379,40 @@ func TestFatalf(t *testing.T) {
 	assert.Equal(t, 1, exited)
 }
 
+func TestPanic(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Level: xlog.LevelFatal, Output: NewOutputChannel(o), NowGetter: func() time.Time { return fakeNow }}).(*logger)
+	assert.PanicsWithValue(t, "test", func() { l.Panic("test", xlog.F{"foo": "bar"}) })
+	last := <-o.w
+	assert.Contains(t, last["file"], "log_test.go:")
+	delete(last, "file")
+	assert.Equal(t, map[string]interface{}{"time": fakeNow, "level": "panic", "message": "test", "foo": "bar"}, last)
+}
+
+func TestPanicf(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: NewOutputChannel(o), NowGetter: func() time.Time { return fakeNow }}).(*logger)
+	assert.PanicsWithValue(t, "test 1", func() { l.Panicf("test %d%v", 1, xlog.F{"foo": "bar"}) })
+	last := <-o.w
+	assert.Contains(t, last["file"], "log_test.go:")
+	delete(last, "file")
+	assert.Equal(t, map[string]interface{}{"time": fakeNow, "level": "panic", "message": "test 1", "foo": "bar"}, last)
+}
+
+func TestPanicEvent(t *testing.T) {
+	o := newTestOutput()
+	l := New(Config{Output: NewOutputChannel(o)})
+	assert.PanicsWithValue(t, "test", func() { l.PanicEvent().Str("foo", "bar").Msg("test") })
+	last := <-o.w
+	assert.Equal(t, "panic", last["level"])
+	assert.Equal(t, "bar", last["foo"])
+
+	l = New(Config{Output: o}).(*logger)
+	l.(*logger).output = nil
+	assert.PanicsWithValue(t, "test 1", func() { l.PanicEvent().Msgf("test %d", 1) })
+	assert.True(t, o.empty())
+}
+
 func TestWrite(t *testing.T) {
 	o := newTestOutput()
 	xl := New(Config{Output: NewOutputChannel(o), NowGetter: func() time.Time { return fakeNow }}).(*logger)